@@ -0,0 +1,602 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// archiveFormat — поддерживаемый формат архива.
+type archiveFormat struct {
+	name    string
+	exts    []string // первое — для новых архивов
+	leveled bool     // есть уровень сжатия
+}
+
+var archiveFormats = []archiveFormat{
+	{name: "zip", exts: []string{".zip", ".jar"}, leveled: true},
+	{name: "tar", exts: []string{".tar"}},
+	{name: "tar.gz", exts: []string{".tar.gz", ".tgz"}, leveled: true},
+	{name: "tar.zst", exts: []string{".tar.zst", ".tzst"}, leveled: true},
+}
+
+const defaultArchiveLevel = 6
+
+// formatByName определяет формат архива по имени файла; -1 — не архив.
+func formatByName(name string) int {
+	lower := strings.ToLower(name)
+	best, bestLen := -1, 0
+	for i, f := range archiveFormats {
+		for _, ext := range f.exts {
+			if strings.HasSuffix(lower, ext) && len(ext) > bestLen {
+				best, bestLen = i, len(ext)
+			}
+		}
+	}
+	return best
+}
+
+// stripArchiveExt убирает расширение архива из имени.
+func stripArchiveExt(name string) string {
+	if i := formatByName(name); i >= 0 {
+		for _, ext := range archiveFormats[i].exts {
+			if strings.HasSuffix(strings.ToLower(name), ext) {
+				return name[:len(name)-len(ext)]
+			}
+		}
+	}
+	return name
+}
+
+// archiveWriter добавляет элементы файловой системы в архив.
+type archiveWriter interface {
+	// add записывает элемент под именем name (через /); link — цель
+	// ссылки, data — содержимое обычного файла, для остальных nil.
+	add(name string, info fs.FileInfo, link string, data io.Reader) error
+	Close() error
+}
+
+// newArchiveWriter создаёт запись в формате archiveFormats[format] с
+// уровнем сжатия 1–9.
+func newArchiveWriter(w io.Writer, format, level int) (archiveWriter, error) {
+	switch archiveFormats[format].name {
+	case "zip":
+		zw := zip.NewWriter(w)
+		zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+		return &zipArchive{zw: zw}, nil
+	case "tar.gz":
+		gz, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			return nil, err
+		}
+		return &tarArchive{tw: tar.NewWriter(gz), compressor: gz}, nil
+	case "tar.zst":
+		zw, err := zstd.NewWriter(w, zstd.WithEncoderLevel(zstdLevel(level)))
+		if err != nil {
+			return nil, err
+		}
+		return &tarArchive{tw: tar.NewWriter(zw), compressor: zw}, nil
+	}
+	return &tarArchive{tw: tar.NewWriter(w)}, nil
+}
+
+// zstdLevel сводит шкалу 1–9 к четырём уровням кодировщика zstd.
+func zstdLevel(level int) zstd.EncoderLevel {
+	switch {
+	case level <= 2:
+		return zstd.SpeedFastest
+	case level <= 5:
+		return zstd.SpeedDefault
+	case level <= 8:
+		return zstd.SpeedBetterCompression
+	}
+	return zstd.SpeedBestCompression
+}
+
+type tarArchive struct {
+	tw         *tar.Writer
+	compressor io.WriteCloser // nil для несжатого tar
+}
+
+func (a *tarArchive) add(name string, info fs.FileInfo, link string, data io.Reader) error {
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if data != nil {
+		_, err = io.Copy(a.tw, data)
+	}
+	return err
+}
+
+func (a *tarArchive) Close() error {
+	err := a.tw.Close()
+	if a.compressor != nil {
+		if cerr := a.compressor.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+type zipArchive struct {
+	zw *zip.Writer
+}
+
+// add: символические ссылки хранятся как в Info-ZIP — с режимом ссылки и
+// целью в качестве содержимого.
+func (a *zipArchive) add(name string, info fs.FileInfo, link string, data io.Reader) error {
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	switch {
+	case info.IsDir():
+		hdr.Name += "/"
+		hdr.Method = zip.Store
+	case info.Mode()&fs.ModeSymlink != 0:
+		hdr.Method = zip.Store
+		data = strings.NewReader(link)
+	default:
+		hdr.Method = zip.Deflate
+	}
+	w, err := a.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		_, err = io.Copy(w, data)
+	}
+	return err
+}
+
+func (a *zipArchive) Close() error {
+	return a.zw.Close()
+}
+
+// archiveProgress считает прочитанные байты и сообщает о каждых 10%.
+type archiveProgress struct {
+	ctx   context.Context
+	r     io.Reader
+	done  *int64
+	total int64
+	next  *int64 // следующий процент для сообщения
+	out   taskOutput
+}
+
+func (p archiveProgress) Read(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := p.r.Read(b)
+	*p.done += int64(n)
+	if p.total > 0 && n > 0 {
+		if pct := *p.done * 100 / p.total; pct >= *p.next {
+			p.out(fmt.Sprintf("%d%% (%s of %s)", pct, humanSize(*p.done), humanSize(p.total)), false)
+			*p.next = pct/10*10 + 10
+		}
+	}
+	return n, err
+}
+
+// packArchive пишет names из dir в архив dst. Архив собирается во
+// временном файле рядом и переименовывается только после успеха.
+func packArchive(ctx context.Context, dir string, names []string, dst string, format, level int, out taskOutput) error {
+	var total int64
+	for _, name := range names {
+		filepath.WalkDir(filepath.Join(dir, name), func(p string, entry fs.DirEntry, err error) error {
+			if err == nil && entry.Type().IsRegular() {
+				if info, err := entry.Info(); err == nil {
+					total += info.Size()
+				}
+			}
+			return nil
+		})
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".nddtc2-pack-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	aw, err := newArchiveWriter(tmp, format, level)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	var done, next int64 = 0, 10
+	files := 0
+	walkErr := func() error {
+		for _, name := range names {
+			err := filepath.WalkDir(filepath.Join(dir, name), func(p string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if p == tmp.Name() {
+					return nil
+				}
+				info, err := entry.Info()
+				if err != nil {
+					return err
+				}
+				rel, err := filepath.Rel(dir, p)
+				if err != nil {
+					return err
+				}
+				arcName := filepath.ToSlash(rel)
+				switch {
+				case info.Mode()&fs.ModeSymlink != 0:
+					link, err := os.Readlink(p)
+					if err != nil {
+						return err
+					}
+					return aw.add(arcName, info, link, nil)
+				case info.IsDir():
+					return aw.add(arcName, info, "", nil)
+				case !info.Mode().IsRegular():
+					out("skipped special file "+arcName, true)
+					return nil
+				}
+				f, err := os.Open(p)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				files++
+				return aw.add(arcName, info, "", archiveProgress{ctx: ctx, r: f, done: &done, total: total, next: &next, out: out})
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+
+	err = aw.Close()
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if walkErr != nil {
+		return walkErr
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s appeared while packing", dst)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return err
+	}
+	if info, err := os.Stat(dst); err == nil {
+		out(fmt.Sprintf("%d files, %s → %s (%s)", files, humanSize(total), dst, humanSize(info.Size())), false)
+	}
+	return nil
+}
+
+// archiveEntry — элемент архива при чтении.
+type archiveEntry struct {
+	name string // очищенный путь через /, "" — небезопасное имя
+	raw  string // имя как в архиве
+	info fs.FileInfo
+	link string // цель символической или жёсткой ссылки
+	hard bool   // жёсткая ссылка tar на более ранний элемент
+}
+
+// cleanEntryName приводит имя из архива к относительному пути внутри него.
+// Абсолютные пути и выход наверх через .. считаются небезопасными.
+func cleanEntryName(raw string) string {
+	name := strings.ReplaceAll(raw, "\\", "/")
+	name = strings.TrimPrefix(name, "./")
+	if strings.HasPrefix(name, "/") || (len(name) > 1 && name[1] == ':') {
+		return ""
+	}
+	name = path.Clean(name)
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return ""
+	}
+	return name
+}
+
+// walkArchive обходит элементы архива по порядку. Для обычных файлов при
+// withData передаётся их содержимое, иначе r == nil.
+func walkArchive(ctx context.Context, archive string, withData bool, fn func(e archiveEntry, r io.Reader) error) error {
+	format := formatByName(archive)
+	if format < 0 {
+		return fmt.Errorf("%s: unknown archive format", filepath.Base(archive))
+	}
+	if archiveFormats[format].name == "zip" {
+		zr, err := zip.OpenReader(archive)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			e := archiveEntry{name: cleanEntryName(f.Name), raw: f.Name, info: f.FileInfo()}
+			if err := zipEntryData(f, e, withData, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	switch archiveFormats[format].name {
+	case "tar.gz":
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case "tar.zst":
+		zr, err := zstd.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	tr := tar.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		e := archiveEntry{name: cleanEntryName(hdr.Name), raw: hdr.Name, info: hdr.FileInfo(), link: hdr.Linkname}
+		if hdr.Typeflag == tar.TypeLink {
+			e.hard = true
+		}
+		var data io.Reader
+		if withData && hdr.Typeflag == tar.TypeReg {
+			data = tr
+		}
+		if err := fn(e, data); err != nil {
+			return err
+		}
+	}
+}
+
+// zipEntryData открывает содержимое элемента zip; у ссылок оно — цель.
+func zipEntryData(f *zip.File, e archiveEntry, withData bool, fn func(e archiveEntry, r io.Reader) error) error {
+	isLink := e.info.Mode()&fs.ModeSymlink != 0
+	if !isLink && (!withData || !e.info.Mode().IsRegular()) {
+		return fn(e, nil)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if isLink {
+		target, err := io.ReadAll(io.LimitReader(rc, 4096))
+		if err != nil {
+			return err
+		}
+		e.link = string(target)
+		return fn(e, nil)
+	}
+	return fn(e, rc)
+}
+
+// withinRoot проверяет, что путь после раскрытия ссылок не выходит за
+// root. Ещё не созданная часть пути ссылок не содержит, поэтому
+// раскрывается ближайший существующий предок.
+func withinRoot(root, p string) bool {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false
+	}
+	existing := p
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return false
+		}
+		existing = parent
+	}
+	real, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(realRoot, real)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// extractArchive распаковывает элемент inner архива (файл или каталог со
+// всем содержимым; "" — весь архив) в dst. Существующие файлы заменяются
+// только при overwrite, иначе пропускаются; права и время получают лишь
+// созданные распаковкой каталоги. Небезопасные имена и запись через ссылки
+// за пределы dst пропускаются с сообщением. out может быть nil.
+func extractArchive(ctx context.Context, archive, inner, dst string, overwrite bool, out taskOutput) error {
+	if out == nil {
+		out = func(string, bool) {}
+	}
+	var total int64
+	if idx, err := openArchiveIndex(archive); err == nil {
+		total = idx.size(inner)
+	}
+	var done, next int64 = 0, 10
+
+	type dirMeta struct {
+		path string
+		info fs.FileInfo
+	}
+	var dirs []dirMeta
+	extracted := make(map[string]string) // имя в архиве → путь на диске
+	found, skipped := false, 0
+
+	// Каталоги, созданные этой распаковкой: уже существовавшим права не меняем
+	made := make(map[string]bool)
+	mkdirAll := func(dir string) error {
+		var missing []string
+		for d := dir; filepath.Dir(d) != d; d = filepath.Dir(d) {
+			if _, err := os.Lstat(d); err == nil {
+				break
+			}
+			missing = append(missing, d)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		for _, d := range missing {
+			made[d] = true
+		}
+		return nil
+	}
+
+	err := walkArchive(ctx, archive, true, func(e archiveEntry, r io.Reader) error {
+		if e.name == "" {
+			if inner == "" {
+				skipped++
+				out("skipped unsafe path "+e.raw, true)
+			}
+			return nil
+		}
+		var rel string
+		switch {
+		case inner == "":
+			rel = e.name
+		case e.name == inner:
+			rel = ""
+		case strings.HasPrefix(e.name, inner+"/"):
+			rel = e.name[len(inner)+1:]
+		default:
+			return nil
+		}
+		found = true
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		if rel != "" {
+			if err := mkdirAll(dst); err != nil {
+				return err
+			}
+			if !withinRoot(dst, filepath.Dir(target)) {
+				skipped++
+				out("skipped "+e.name+": path leads outside "+dst, true)
+				return nil
+			}
+		}
+		if err := mkdirAll(filepath.Dir(target)); err != nil {
+			return err
+		}
+
+		mode := e.info.Mode()
+		if !e.info.IsDir() {
+			if info, err := os.Lstat(target); err == nil && !overwrite {
+				skipped++
+				out("skipped "+e.name+": already exists", true)
+				return nil
+			} else if err == nil && !info.IsDir() {
+				// Удаляем саму цель, а не пишем в неё: она может быть ссылкой
+				if err := os.Remove(target); err != nil {
+					return err
+				}
+			}
+		}
+		switch {
+		case e.info.IsDir():
+			if err := os.Mkdir(target, 0755); err == nil {
+				made[target] = true
+			} else if !os.IsExist(err) {
+				return err
+			}
+			if made[target] {
+				dirs = append(dirs, dirMeta{target, e.info})
+			}
+			return nil
+		case e.hard:
+			src, ok := extracted[cleanEntryName(e.link)]
+			if !ok {
+				skipped++
+				out("skipped hard link "+e.name+": target "+e.link+" not extracted", true)
+				return nil
+			}
+			return os.Link(src, target)
+		case mode&fs.ModeSymlink != 0:
+			return os.Symlink(e.link, target)
+		case !mode.IsRegular():
+			skipped++
+			out("skipped special file "+e.name, true)
+			return nil
+		}
+
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode.Perm())
+		if err != nil {
+			return err
+		}
+		if r != nil {
+			_, err = io.Copy(f, archiveProgress{ctx: ctx, r: r, done: &done, total: total, next: &next, out: out})
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		extracted[e.name] = target
+		return os.Chtimes(target, time.Time{}, e.info.ModTime())
+	})
+	// Права и время каталогов — после файлов: иначе режим без w помешал бы
+	// записи, а запись в каталог сдвинула бы время
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Chmod(dirs[i].path, dirs[i].info.Mode().Perm())
+		os.Chtimes(dirs[i].path, time.Time{}, dirs[i].info.ModTime())
+	}
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%s: no such entry in %s", inner, filepath.Base(archive))
+	}
+	if skipped > 0 {
+		return fmt.Errorf("%d entries skipped", skipped)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanEntryName(t *testing.T) {
+	tests := []struct{ raw, want string }{
+		{"dir/file.txt", "dir/file.txt"},
+		{"./dir/", "dir"},
+		{`dir\sub\file`, "dir/sub/file"},
+		{"a/../b", "b"},
+		{"a//b/./c", "a/b/c"},
+		{"/etc/passwd", ""},
+		{`\windows\system32`, ""},
+		{"C:/x", ""},
+		{"c:evil", ""},
+		{"..", ""},
+		{"../x", ""},
+		{"a/../../x", ""},
+		{".", ""},
+		{"./", ""},
+		{"..hidden", "..hidden"},
+	}
+	for _, tt := range tests {
+		if got := cleanEntryName(tt.raw); got != tt.want {
+			t.Errorf("cleanEntryName(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestWithinRoot(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("b", filepath.Join(root, "a", "inner")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		p    string
+		want bool
+	}{
+		{root, true},
+		{filepath.Join(root, "a", "b"), true},
+		{filepath.Join(root, "a", "missing", "deeper"), true},
+		{filepath.Join(root, "a", "inner"), true},
+		{filepath.Join(root, "escape"), false},
+		{filepath.Join(root, "escape", "new"), false},
+		{filepath.Join(root, ".."), false},
+		{outside, false},
+	}
+	for _, tt := range tests {
+		if got := withinRoot(root, tt.p); got != tt.want {
+			t.Errorf("withinRoot(%q) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+// TestExtractArchiveUnsafe проверяет, что распаковка не пишет за пределы
+// каталога назначения ни через имена, ни через ссылки из самого архива.
+func TestExtractArchiveUnsafe(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	archive := filepath.Join(dir, "evil.tar")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	add := func(h *tar.Header, body string) {
+		h.Size = int64(len(body))
+		if h.Mode == 0 {
+			h.Mode = 0644
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(&tar.Header{Name: "ok.txt", Typeflag: tar.TypeReg}, "fine")
+	add(&tar.Header{Name: "../slip.txt", Typeflag: tar.TypeReg}, "x")
+	add(&tar.Header{Name: filepath.Join(outside, "abs.txt"), Typeflag: tar.TypeReg}, "x")
+	add(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside}, "")
+	add(&tar.Header{Name: "link/through.txt", Typeflag: tar.TypeReg}, "x")
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	dst := filepath.Join(dir, "out")
+	err = extractArchive(context.Background(), archive, "", dst, false, nil)
+	if err == nil {
+		t.Fatal("expected unsafe entries to be reported")
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "ok.txt")); err != nil || string(data) != "fine" {
+		t.Errorf("ok.txt = %q, %v", data, err)
+	}
+	for _, p := range []string{
+		filepath.Join(dir, "slip.txt"),
+		filepath.Join(outside, "abs.txt"),
+		filepath.Join(outside, "through.txt"),
+	} {
+		if _, err := os.Lstat(p); err == nil {
+			t.Errorf("%s was written outside the destination", p)
+		}
+	}
+}
+
+func TestExtractArchiveKeepsExisting(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "a.tar")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	tw.WriteHeader(&tar.Header{Name: "sub/", Typeflag: tar.TypeDir, Mode: 0555})
+	tw.WriteHeader(&tar.Header{Name: "sub/a", Typeflag: tar.TypeReg, Mode: 0644, Size: 3})
+	tw.Write([]byte("new"))
+	tw.Close()
+	f.Close()
+
+	dst := filepath.Join(dir, "out")
+	if err := os.MkdirAll(filepath.Join(dst, "sub"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dst, "sub", "a"), []byte("old"), 0644)
+
+	if err := extractArchive(context.Background(), archive, "", dst, false, nil); err == nil {
+		t.Error("expected the existing file to be reported as skipped")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "sub", "a")); string(data) != "old" {
+		t.Errorf("existing file overwritten without confirmation: %q", data)
+	}
+	if err := extractArchive(context.Background(), archive, "", dst, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "sub", "a")); string(data) != "new" {
+		t.Errorf("confirmed overwrite left %q", data)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "sub")); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("existing directory mode changed: %v, %v", info.Mode(), err)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// builtin — команда, которую выполняет само приложение, без внешних
+// процессов и без проверки политикой.
+type builtin struct {
+	run      func(m *model, args []string) tea.Cmd
+	complete completer // nil — fileCompleter
+}
+
+// builtins — реестр встроенных команд. Команды с долгой работой (find, du)
+// возвращают tea.Cmd, присылающий builtinOutputMsg.
+var builtins = map[string]builtin{
+	"cd":         {run: builtinCd, complete: dirCompleter},
+	"pushd":      {run: builtinPushd, complete: dirCompleter},
+	"popd":       {run: builtinPopd},
+	"dirs":       {run: builtinDirs},
+	"export":     {run: builtinExport},
+	"jobs":       {run: (*model).jobBuiltin},
+	"fg":         {run: (*model).jobBuiltin, complete: jobCompleter},
+	"kill":       {run: (*model).jobBuiltin, complete: jobCompleter},
+	"wait":       {run: (*model).jobBuiltin, complete: jobCompleter},
+	"scrollback": {run: (*model).scrollbackBuiltin},
+	"mkdir":      {run: builtinMkdir, complete: dirCompleter},
+	"touch":      {run: builtinTouch},
+	"cp":         {run: builtinCp},
+	"mv":         {run: builtinMv},
+	"rm":         {run: builtinRm},
+	"find":       {run: builtinFind, complete: dirCompleter},
+	"du":         {run: builtinDu},
+	"ln":         {run: builtinLn},
+}
+
+func init() {
+	for name, b := range builtins {
+		builtinCommands = append(builtinCommands, name)
+		if b.complete != nil {
+			argCompleters[name] = b.complete
+		}
+	}
+	sort.Strings(builtinCommands)
+}
+
+// builtinOutputMsg — результат фоновой встроенной команды.
+type builtinOutputMsg struct {
+	Lines []string
+}
+
+// runBuiltin выполняет встроенную команду; false — в конвейере её нет.
+func (m *model) runBuiltin(p *pipeline, input string) (bool, tea.Cmd) {
+	var found string
+	for _, st := range p.Stages {
+		if _, ok := builtins[st.Args[0]]; ok {
+			found = st.Args[0]
+			break
+		}
+	}
+	if found == "" {
+		return false, nil
+	}
+	m.termOutput.add("$ " + input)
+	st := p.Stages[0]
+	if len(p.Stages) > 1 || p.Background || st.Stdin != "" || st.Stdout != "" {
+		m.termOutput.add(found + ": builtins can't be used with pipes, redirections or &")
+		return true, nil
+	}
+	return true, builtins[found].run(m, st.Args)
+}
+
+// parseFlags разбирает короткие флаги вида -rf из allowed; "--" завершает флаги.
+func parseFlags(name string, args []string, allowed string) (map[rune]bool, []string, error) {
+	flags := make(map[rune]bool)
+	var operands []string
+	for i, a := range args {
+		if a == "--" {
+			return flags, append(operands, args[i+1:]...), nil
+		}
+		if len(a) < 2 || a[0] != '-' {
+			operands = append(operands, a)
+			continue
+		}
+		for _, r := range a[1:] {
+			if !strings.ContainsRune(allowed, r) {
+				return nil, nil, fmt.Errorf("%s: unknown option -%c", name, r)
+			}
+			flags[r] = true
+		}
+	}
+	return flags, operands, nil
+}
+
+var dirCompleter = completerFunc(func(ctx completionContext) []string {
+	return completePaths(ctx.dir, ctx.word, true)
+})
+
+func builtinCd(m *model, args []string) tea.Cmd {
+	newPath := os.Getenv("HOME")
+	if len(args) > 1 {
+		newPath = resolvePath(m.activeDir(), args[1])
+	}
+	if fi, err := os.Stat(newPath); err != nil || !fi.IsDir() {
+		m.termOutput.add("cd: no such directory: " + newPath)
+		return nil
+	}
+	m.setActiveDir(newPath)
+	m.termOutput.add("--> cd " + newPath)
+	return nil
+}
+
+// pushd запоминает текущий каталог в стеке и переходит в новый; без
+// аргументов меняет местами текущий каталог и вершину стека.
+func builtinPushd(m *model, args []string) tea.Cmd {
+	cur := m.activeDir()
+	var target string
+	if len(args) < 2 {
+		if len(m.dirStack) == 0 {
+			m.termOutput.add("pushd: no other directory")
+			return nil
+		}
+		target = m.dirStack[len(m.dirStack)-1]
+		m.dirStack[len(m.dirStack)-1] = cur
+	} else {
+		target = resolvePath(cur, args[1])
+		if fi, err := os.Stat(target); err != nil || !fi.IsDir() {
+			m.termOutput.add("pushd: no such directory: " + target)
+			return nil
+		}
+		m.dirStack = append(m.dirStack, cur)
+	}
+	m.setActiveDir(target)
+	return builtinDirs(m, nil)
+}
+
+func builtinPopd(m *model, args []string) tea.Cmd {
+	if len(m.dirStack) == 0 {
+		m.termOutput.add("popd: directory stack empty")
+		return nil
+	}
+	target := m.dirStack[len(m.dirStack)-1]
+	m.dirStack = m.dirStack[:len(m.dirStack)-1]
+	if fi, err := os.Stat(target); err != nil || !fi.IsDir() {
+		m.termOutput.add("popd: no such directory: " + target)
+		return nil
+	}
+	m.setActiveDir(target)
+	return builtinDirs(m, nil)
+}
+
+// dirs печатает стек каталогов, начиная с текущего.
+func builtinDirs(m *model, args []string) tea.Cmd {
+	list := []string{m.activeDir()}
+	for i := len(m.dirStack) - 1; i >= 0; i-- {
+		list = append(list, m.dirStack[i])
+	}
+	m.termOutput.add(strings.Join(list, " "))
+	return nil
+}
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// export задаёт переменные окружения самого приложения: их видят $VAR
+// в командной строке и все запускаемые программы.
+func builtinExport(m *model, args []string) tea.Cmd {
+	if len(args) == 1 {
+		env := os.Environ()
+		sort.Strings(env)
+		for _, kv := range env {
+			name, value, _ := strings.Cut(kv, "=")
+			m.termOutput.add(fmt.Sprintf("export %s=%s", name, quoteArg(value)))
+		}
+		return nil
+	}
+	for _, a := range args[1:] {
+		name, value, hasValue := strings.Cut(a, "=")
+		if !envName.MatchString(name) {
+			m.termOutput.add("export: not a valid identifier: " + name)
+			continue
+		}
+		if !hasValue {
+			value = os.Getenv(name)
+		}
+		if err := os.Setenv(name, value); err != nil {
+			m.termOutput.add("export: " + err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type compareStatus int
+
+const (
+	cmpNone      compareStatus = iota
+	cmpOnlyHere                // есть только в этой панели
+	cmpNewer                   // в этой панели новее
+	cmpOlder                   // в этой панели старше
+	cmpIdentical               // совпадает с другой панелью
+	cmpDiffers                 // отличается, но mtime одинаковый (или разные типы)
+)
+
+// compareResult — результат сравнения содержимого двух панелей.
+type compareResult struct {
+	leftDir, rightDir string
+	left, right       map[string]compareStatus
+	hashed            bool
+}
+
+type compareDoneMsg struct {
+	Result *compareResult
+}
+
+// valid сообщает, относится ли результат к текущим каталогам панелей.
+func (r *compareResult) valid(leftDir, rightDir string) bool {
+	return r != nil && r.leftDir == leftDir && r.rightDir == rightDir
+}
+
+// compareDirsAsync сравнивает элементы панелей по имени, размеру и mtime,
+// а при hash=true дополнительно по содержимому (sha256).
+func compareDirsAsync(leftDir, rightDir string, leftItems, rightItems []string, hash bool) tea.Cmd {
+	return func() tea.Msg {
+		res := &compareResult{
+			leftDir:  leftDir,
+			rightDir: rightDir,
+			left:     make(map[string]compareStatus),
+			right:    make(map[string]compareStatus),
+			hashed:   hash,
+		}
+
+		rightSet := make(map[string]bool, len(rightItems))
+		for _, name := range rightItems {
+			rightSet[name] = true
+		}
+
+		for _, name := range leftItems {
+			if !rightSet[name] {
+				res.left[name] = cmpOnlyHere
+				continue
+			}
+			l, r := compareEntries(filepath.Join(leftDir, name), filepath.Join(rightDir, name), hash)
+			res.left[name] = l
+			res.right[name] = r
+		}
+		for _, name := range rightItems {
+			if _, ok := res.right[name]; !ok {
+				res.right[name] = cmpOnlyHere
+			}
+		}
+
+		return compareDoneMsg{Result: res}
+	}
+}
+
+// compareEntries возвращает статусы пары одноимённых элементов слева и справа.
+// Каталоги сравниваются только по наличию — рекурсивное сравнение делает синхронизация.
+func compareEntries(leftPath, rightPath string, hash bool) (compareStatus, compareStatus) {
+	li, lerr := os.Stat(leftPath)
+	ri, rerr := os.Stat(rightPath)
+	if lerr != nil || rerr != nil {
+		return cmpDiffers, cmpDiffers
+	}
+	if li.IsDir() || ri.IsDir() {
+		if li.IsDir() && ri.IsDir() {
+			return cmpIdentical, cmpIdentical
+		}
+		return cmpDiffers, cmpDiffers
+	}
+
+	// Секундная точность: разные ФС хранят mtime с разной гранулярностью.
+	lt := li.ModTime().Truncate(time.Second)
+	rt := ri.ModTime().Truncate(time.Second)
+
+	if li.Size() == ri.Size() {
+		if hash {
+			if same, err := sameContent(leftPath, rightPath); err == nil && same {
+				return cmpIdentical, cmpIdentical
+			}
+		} else if lt.Equal(rt) {
+			return cmpIdentical, cmpIdentical
+		}
+	}
+
+	switch {
+	case lt.After(rt):
+		return cmpNewer, cmpOlder
+	case lt.Before(rt):
+		return cmpOlder, cmpNewer
+	}
+	return cmpDiffers, cmpDiffers
+}
+
+// sameContent сравнивает sha256 двух файлов.
+func sameContent(a, b string) (bool, error) {
+	ha, err := fileHash(a)
+	if err != nil {
+		return false, err
+	}
+	hb, err := fileHash(b)
+	if err != nil {
+		return false, err
+	}
+	return ha == hb, nil
+}
+
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// compareSummary формирует строку-итог для вывода в терминал.
+func compareSummary(r *compareResult) string {
+	count := func(marks map[string]compareStatus, st compareStatus) int {
+		n := 0
+		for _, s := range marks {
+			if s == st {
+				n++
+			}
+		}
+		return n
+	}
+	mode := "size+mtime"
+	if r.hashed {
+		mode = "content"
+	}
+	return fmt.Sprintf("Compare (%s): %d only-left, %d only-right, %d newer left, %d newer right, %d identical, %d differ",
+		mode,
+		count(r.left, cmpOnlyHere),
+		count(r.right, cmpOnlyHere),
+		count(r.left, cmpNewer),
+		count(r.right, cmpNewer),
+		count(r.left, cmpIdentical),
+		count(r.left, cmpDiffers),
+	)
+}
+
+// compareMarks возвращает отметки сравнения для панели или nil, если они
+// устарели: панели сменили каталог. После перечитывания панели (c/p,
+// удаление, переименование) reloadPanel сбрасывает их сам.
+func (m model) compareMarks(panel int) map[string]compareStatus {
+	if !m.compare.valid(m.leftDir, m.rightDir) {
+		return nil
+	}
+	if panel == 0 {
+		return m.compare.left
+	}
+	return m.compare.right
+}
+
+// selectByCompare выделяет в активной панели элементы с подходящим статусом.
+func (m *model) selectByCompare(match func(compareStatus) bool) int {
+	marks := m.compareMarks(m.activePanel)
+	if marks == nil {
+		return 0
+	}
+	selected := m.selectedLeft
+	if m.activePanel == 1 {
+		selected = m.selectedRight
+	}
+	n := 0
+	for name, st := range marks {
+		if match(st) {
+			selected[name] = true
+			n++
+		}
+	}
+	return n
+}
+
+// compareTag — короткая метка статуса, выводимая перед именем элемента.
+func compareTag(st compareStatus) string {
+	switch st {
+	case cmpOnlyHere:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Render("+")
+	case cmpNewer:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render(">")
+	case cmpOlder:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Render("<")
+	case cmpIdentical:
+		return lipgloss.NewStyle().Faint(true).Render("=")
+	case cmpDiffers:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("≠")
+	}
+	return " "
+}
+
+// panelLabel — название панели для сообщений ("left"/"right").
+func panelLabel(panel int) string {
+	if panel == 0 {
+		return "left"
+	}
+	return "right"
+}
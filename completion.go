@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// completionContext — что известно о дополняемом слове.
+type completionContext struct {
+	line     string   // ввод до курсора
+	word     string   // дополняемое слово без экранирования
+	typed    string   // то же слово, как оно набрано
+	argIndex int      // 0 — имя команды
+	command  string   // имя команды (пусто, если дополняем его само)
+	dir      string   // каталог активной панели
+	selected []string // выделенные элементы активной панели
+	jobs     []string // фоновые задания в виде %N
+}
+
+// completer предлагает варианты дополнения аргументов команды.
+type completer interface {
+	Complete(ctx completionContext) []string
+}
+
+type completerFunc func(ctx completionContext) []string
+
+func (f completerFunc) Complete(ctx completionContext) []string { return f(ctx) }
+
+// argCompleters — дополнение аргументов для конкретных команд; остальные
+// получают fileCompleter. Встроенные команды регистрируют здесь свои.
+var argCompleters = map[string]completer{}
+
+// jobCompleter дополняет ссылки на фоновые задания.
+var jobCompleter = completerFunc(func(ctx completionContext) []string {
+	var out []string
+	for _, j := range ctx.jobs {
+		if strings.HasPrefix(j, ctx.word) {
+			out = append(out, j)
+		}
+	}
+	return out
+})
+
+// fileCompleter дополняет выделенные элементы панели и пути относительно её каталога.
+var fileCompleter = completerFunc(func(ctx completionContext) []string {
+	var out []string
+	for _, name := range ctx.selected {
+		if strings.HasPrefix(name, ctx.word) {
+			out = append(out, name)
+		}
+	}
+	return append(out, completePaths(ctx.dir, ctx.word, false)...)
+})
+
+// builtinCommands — команды, выполняемые самим приложением (из реестра builtins).
+var builtinCommands []string
+
+// completionMenu — варианты, показываемые над строкой ввода.
+type completionMenu struct {
+	candidates []string
+	index      int // -1 — ничего не выбрано, показан общий префикс
+	start      int // начало дополняемого слова в строке (в рунах)
+	end        int // позиция курсора
+	tail       string
+}
+
+// completePaths перечисляет файлы каталога, подходящие под word; dirsOnly — только каталоги.
+func completePaths(base, word string, dirsOnly bool) []string {
+	dirPart, prefix := "", word
+	if i := strings.LastIndex(word, "/"); i >= 0 {
+		dirPart, prefix = word[:i+1], word[i+1:]
+	}
+
+	lookup := dirPart
+	if strings.HasPrefix(lookup, "~/") {
+		lookup = filepath.Join(os.Getenv("HOME"), lookup[2:])
+	} else if !filepath.IsAbs(lookup) {
+		lookup = filepath.Join(base, lookup)
+	}
+
+	entries, err := os.ReadDir(lookup)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		isDir := e.IsDir()
+		if e.Type()&os.ModeSymlink != 0 {
+			if fi, err := os.Stat(filepath.Join(lookup, name)); err == nil {
+				isDir = fi.IsDir()
+			}
+		}
+		if dirsOnly && !isDir {
+			continue
+		}
+		if isDir {
+			name += "/"
+		}
+		out = append(out, dirPart+name)
+	}
+	return out
+}
+
+// commandCandidates — имена команд: при политике с default=ask подходит
+// любая программа из $PATH, иначе только явно разрешённые правилами.
+func (m model) commandCandidates(prefix string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	add := func(name string) {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	for _, b := range builtinCommands {
+		add(b)
+	}
+	p := m.policy.policy
+	for _, r := range p.Rules {
+		if r.Action != policyBlock && !strings.ContainsAny(r.Command, "*?[") {
+			add(r.Command)
+		}
+	}
+	if p.Default == policyAsk {
+		for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				if info, err := e.Info(); err == nil && !e.IsDir() && info.Mode()&0111 != 0 {
+					add(e.Name())
+				}
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// completions собирает варианты для слова под курсором.
+func (m model) completions(line string) (completionContext, []string) {
+	ctx := completionContext{line: line, dir: m.activeDir()}
+	fields, open := typedWords(line)
+	if open {
+		ctx.typed = fields[len(fields)-1]
+		ctx.word = unescapeWord(ctx.typed)
+		ctx.argIndex = len(fields) - 1
+	} else {
+		ctx.argIndex = len(fields)
+	}
+	if ctx.argIndex == 0 {
+		return ctx, m.commandCandidates(ctx.word)
+	}
+
+	ctx.command = unescapeWord(fields[0])
+	selected := m.selectedLeft
+	if m.activePanel == 1 {
+		selected = m.selectedRight
+	}
+	for name := range selected {
+		ctx.selected = append(ctx.selected, name)
+	}
+	sort.Strings(ctx.selected)
+	for _, j := range m.jobs {
+		ctx.jobs = append(ctx.jobs, fmt.Sprintf("%%%d", j.Job))
+	}
+
+	c, ok := argCompleters[ctx.command]
+	if !ok {
+		c = fileCompleter
+	}
+	seen := make(map[string]bool)
+	var out []string
+	for _, cand := range c.Complete(ctx) {
+		if !seen[cand] {
+			seen[cand] = true
+			out = append(out, cand)
+		}
+	}
+	return ctx, out
+}
+
+// typedWords делит текущую стадию конвейера на слова так, как они набраны:
+// пробел или | после обратного слеша слово не разрывают. open — курсор
+// стоит в последнем слове, а не после пробела.
+func typedWords(line string) (words []string, open bool) {
+	var cur strings.Builder
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+			open = true
+		case r == '\\':
+			cur.WriteRune(r)
+			escaped = true
+			open = true
+		case r == '|':
+			// Дополняем только текущую стадию конвейера
+			words, open = nil, false
+			cur.Reset()
+		case r == ' ' || r == '\t':
+			if open {
+				words = append(words, cur.String())
+				cur.Reset()
+				open = false
+			}
+		default:
+			cur.WriteRune(r)
+			open = true
+		}
+	}
+	if open {
+		words = append(words, cur.String())
+	}
+	return words, open
+}
+
+// unescapeWord убирает экранирование обратным слешем.
+func unescapeWord(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapeWord экранирует обратным слешем символы, которые токенизатор понял
+// бы иначе. В отличие от кавычек (quoteArg) так подставленное слово можно
+// набирать и дополнять дальше.
+func escapeWord(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if strings.ContainsRune(" \t'\"\\|&<>$*?[]", r) || i == 0 && r == '~' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func commonPrefix(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	prefix := items[0]
+	for _, s := range items[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			// По рунам, а не байтам: иначе от кириллицы остаётся половина символа
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
+
+// replaceWord подставляет text (уже экранированный) вместо дополняемого слова.
+func (m *model) replaceWord(menu *completionMenu, text string) {
+	runes := []rune(m.termInput.Value())
+	head := string(runes[:menu.start])
+	m.termInput.SetValue(head + text + menu.tail)
+	m.termInput.SetCursor(len([]rune(head + text)))
+	menu.end = menu.start + len([]rune(text))
+}
+
+// complete обрабатывает Tab: единственный вариант подставляется сразу,
+// при нескольких — общий префикс и меню; повторный Tab листает меню.
+func (m *model) complete() {
+	if menu := m.completion; menu != nil && len(menu.candidates) > 1 {
+		menu.index = (menu.index + 1) % len(menu.candidates)
+		m.replaceWord(menu, escapeWord(menu.candidates[menu.index]))
+		return
+	}
+
+	runes := []rune(m.termInput.Value())
+	pos := m.termInput.Position()
+	line := string(runes[:pos])
+	ctx, candidates := m.completions(line)
+	if len(candidates) == 0 {
+		m.completion = nil
+		return
+	}
+
+	menu := &completionMenu{
+		candidates: candidates,
+		index:      -1,
+		start:      pos - len([]rune(ctx.typed)),
+		end:        pos,
+		tail:       string(runes[pos:]),
+	}
+	if len(candidates) == 1 {
+		text := escapeWord(candidates[0])
+		if !strings.HasSuffix(text, "/") {
+			text += " "
+		}
+		m.replaceWord(menu, text)
+		m.completion = nil
+		return
+	}
+	if prefix := commonPrefix(candidates); len(prefix) > len(ctx.word) {
+		m.replaceWord(menu, escapeWord(prefix))
+	}
+	m.completion = menu
+}
+
+// render — строки меню дополнения (не более maxLines), с прокруткой к выбранному.
+func (menu *completionMenu) render(maxLines, width int) []string {
+	if maxLines < 1 {
+		return nil
+	}
+	colW := 0
+	for _, c := range menu.candidates {
+		if w := lipgloss.Width(c); w > colW {
+			colW = w
+		}
+	}
+	colW += 2
+	perLine := width / colW
+	if perLine < 1 {
+		perLine = 1
+	}
+
+	rows := (len(menu.candidates) + perLine - 1) / perLine
+	first := 0
+	if sel := menu.index / perLine; menu.index >= 0 && sel >= maxLines {
+		first = sel - maxLines + 1
+	}
+
+	var lines []string
+	for r := first; r < rows && r < first+maxLines; r++ {
+		var b strings.Builder
+		for i := r * perLine; i < (r+1)*perLine && i < len(menu.candidates); i++ {
+			c := menu.candidates[i]
+			pad := strings.Repeat(" ", colW-lipgloss.Width(c))
+			if i == menu.index {
+				c = lipgloss.NewStyle().Reverse(true).Render(c)
+			}
+			b.WriteString(c + pad)
+		}
+		lines = append(lines, b.String())
+	}
+	if first+maxLines < rows {
+		lines[len(lines)-1] += lipgloss.NewStyle().Faint(true).Render("…")
+	}
+	return lines
+}
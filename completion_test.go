@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		items []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"alpha"}, "alpha"},
+		{[]string{"alpha", "alps", "al"}, "al"},
+		{[]string{"abc", "xyz"}, ""},
+		// Общий первый байт у «д» и «е», но не общая руна
+		{[]string{"дом", "ель"}, ""},
+		{[]string{"документ", "доклад"}, "док"},
+	}
+	for _, tt := range tests {
+		if got := commonPrefix(tt.items); got != tt.want {
+			t.Errorf("commonPrefix(%q) = %q, want %q", tt.items, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeWord(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"plain.txt", "plain.txt"},
+		{"my file", `my\ file`},
+		{"a|b&c", `a\|b\&c`},
+		{"it's", `it\'s`},
+		{"~home", `\~home`},
+		{"a~b", "a~b"},
+		{"*.go", `\*.go`},
+		{"файл 1", `файл\ 1`},
+	}
+	for _, tt := range tests {
+		got := escapeWord(tt.in)
+		if got != tt.want {
+			t.Errorf("escapeWord(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if back := unescapeWord(got); back != tt.in {
+			t.Errorf("unescapeWord(%q) = %q, want %q", got, back, tt.in)
+		}
+		// Токенизатор должен прочитать экранированное слово как одно исходное
+		tokens, err := tokenize(got, testLookup)
+		if err != nil || len(tokens) != 1 || tokens[0].text != tt.in || tokens[0].glob {
+			t.Errorf("tokenize(%q) = %+v, %v, want one literal %q", got, tokens, err, tt.in)
+		}
+	}
+}
+
+func TestTypedWords(t *testing.T) {
+	tests := []struct {
+		line  string
+		words []string
+		open  bool
+	}{
+		{"", nil, false},
+		{"ls", []string{"ls"}, true},
+		{"ls ", []string{"ls"}, false},
+		{`cat my\ fi`, []string{"cat", `my\ fi`}, true},
+		{`ls -l | gr`, []string{"gr"}, true},
+		{`ls | `, nil, false},
+		{`echo a\|b`, []string{"echo", `a\|b`}, true},
+		{"  cd   до", []string{"cd", "до"}, true},
+	}
+	for _, tt := range tests {
+		words, open := typedWords(tt.line)
+		if !reflect.DeepEqual(words, tt.words) || open != tt.open {
+			t.Errorf("typedWords(%q) = %q, %v, want %q, %v", tt.line, words, open, tt.words, tt.open)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const defaultScrollback = 10000
+
+// appConfig — общие настройки из configDir()/config.json. Отсутствующие
+// поля получают значения по умолчанию.
+type appConfig struct {
+	Scrollback int `json:"scrollback"` // сколько строк вывода хранит терминал
+}
+
+func defaultConfig() appConfig {
+	return appConfig{Scrollback: defaultScrollback}
+}
+
+func configPath() string {
+	return filepath.Join(configDir(), "config.json")
+}
+
+// loadConfig читает настройки; при ошибке возвращает значения по умолчанию
+// вместе с ошибкой, чтобы её можно было показать в терминале.
+func loadConfig() (appConfig, error) {
+	cfg := defaultConfig()
+	data, err := os.ReadFile(configPath())
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return defaultConfig(), fmt.Errorf("%s: %w", configPath(), err)
+	}
+	if cfg.Scrollback < 100 {
+		cfg.Scrollback = 100
+	}
+	return cfg, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// confirmDialog — вопрос да/нет; onYes выполняется при подтверждении.
+type confirmDialog struct {
+	prompt string
+	onYes  func(m *model) tea.Cmd
+}
+
+func (m model) updateConfirm(msg tea.KeyMsg) (model, tea.Cmd) {
+	c := m.confirm
+	switch msg.String() {
+	case "y", "Y", "enter":
+		m.confirm = nil
+		cmd := c.onYes(&m)
+		return m, cmd
+	case "n", "N", "esc":
+		m.confirm = nil
+		m.termOutput.add("Cancelled: " + c.prompt)
+	}
+	return m, nil
+}
+
+func (m model) renderConfirm() string {
+	popupStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("214")).
+		Padding(1, 2).
+		Width(50)
+
+	title := lipgloss.NewStyle().Bold(true).Render("Confirm")
+	hint := lipgloss.NewStyle().Faint(true).Render("y/Enter yes • n/Esc no")
+
+	content := lipgloss.JoinVertical(lipgloss.Left, title, "", m.confirm.prompt, "", hint)
+	popup := popupStyle.Render(content)
+
+	x := (m.width - 50) / 2
+	y := (m.height - 9) / 2
+	if y < 0 {
+		y = 0
+	}
+	return lipgloss.NewStyle().MarginLeft(x).MarginTop(y).Render(popup)
+}
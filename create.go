@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type createKind int
+
+const (
+	createFile createKind = iota
+	createDir
+	createTemplate
+)
+
+// createDialog — окно создания файла, каталога или элемента из шаблона.
+type createDialog struct {
+	kind      createKind
+	dir       string
+	templates []os.DirEntry
+	cursor    int
+	picking   bool // шаблон ещё не выбран
+	input     textinput.Model
+	err       string
+}
+
+// templatesDir — каталог шаблонов: файлы и заготовки каталогов.
+func templatesDir() string {
+	return filepath.Join(configDir(), "templates")
+}
+
+func listTemplates() ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(templatesDir())
+	if err != nil {
+		return nil, err
+	}
+	var list []os.DirEntry
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), ".") {
+			list = append(list, e)
+		}
+	}
+	return list, nil
+}
+
+func (m *model) openCreate(kind createKind) tea.Cmd {
+	if m.refuseInArchive("Create") {
+		return nil
+	}
+	d := &createDialog{kind: kind, dir: m.activeDir()}
+	d.input = textinput.New()
+	d.input.CharLimit = 1024
+	d.input.Width = 50
+	if kind == createTemplate {
+		list, err := listTemplates()
+		if err != nil && !os.IsNotExist(err) {
+			m.termOutput.add("Templates: " + err.Error())
+			return nil
+		}
+		if len(list) == 0 {
+			m.termOutput.add("No templates: put files or directory skeletons into " + templatesDir())
+			return nil
+		}
+		d.templates = list
+		d.picking = true
+		m.create = d
+		return nil
+	}
+	m.create = d
+	return d.input.Focus()
+}
+
+func (m model) updateCreate(msg tea.KeyMsg) (model, tea.Cmd) {
+	d := m.create
+	if d.picking {
+		switch msg.String() {
+		case "esc", "q":
+			m.create = nil
+		case "up", "k":
+			if d.cursor > 0 {
+				d.cursor--
+			}
+		case "down", "j":
+			if d.cursor < len(d.templates)-1 {
+				d.cursor++
+			}
+		case "enter":
+			d.picking = false
+			d.input.SetValue(d.templates[d.cursor].Name())
+			return m, d.input.Focus()
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc":
+		m.create = nil
+		return m, nil
+	case "enter":
+		if err := m.submitCreate(); err != nil {
+			d.err = err.Error()
+		}
+		return m, nil
+	}
+	d.err = ""
+	var cmd tea.Cmd
+	d.input, cmd = d.input.Update(msg)
+	return m, cmd
+}
+
+// checkNewPath проверяет относительный путь нового элемента: вложенные
+// каталоги допускаются, выход за пределы панели — нет.
+func checkNewPath(rel string) error {
+	if rel == "" {
+		return fmt.Errorf("empty name")
+	}
+	if filepath.IsAbs(rel) {
+		return fmt.Errorf("%q is not relative to the panel", rel)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if part == "" {
+			continue
+		}
+		if err := validName(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *model) submitCreate() error {
+	d := m.create
+	rel := d.input.Value()
+	if err := checkNewPath(rel); err != nil {
+		return err
+	}
+	target := filepath.Join(d.dir, rel)
+	if _, err := os.Lstat(target); err == nil {
+		return fmt.Errorf("%s already exists", rel)
+	}
+
+	var what string
+	switch d.kind {
+	case createDir:
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+		what = "directory"
+	case createFile:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		f.Close()
+		what = "file"
+	case createTemplate:
+		tmpl := d.templates[d.cursor]
+		vars := templateVars(target)
+		if err := instantiateTemplate(filepath.Join(templatesDir(), tmpl.Name()), target, vars); err != nil {
+			return err
+		}
+		what = "from template " + tmpl.Name()
+	}
+
+	m.create = nil
+	m.termOutput.add(fmt.Sprintf("Created %s %s", what, target))
+	m.refreshPanelsAfterChange(d.dir)
+	if m.activeDir() == d.dir {
+		// Курсор — на созданный элемент верхнего уровня
+		top, _, _ := strings.Cut(filepath.ToSlash(filepath.Clean(rel)), "/")
+		for i, name := range m.activeItems() {
+			if name == top {
+				m.setCursor(i)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// templateVars — значения для {{...}} в шаблоне, создаваемом по пути target.
+func templateVars(target string) map[string]string {
+	file := filepath.Base(target)
+	stem, _ := splitExt(file)
+	now := time.Now()
+	return map[string]string{
+		"name": stem,
+		"file": file,
+		"dir":  filepath.Base(filepath.Dir(target)),
+		"date": now.Format("2006-01-02"),
+		"time": now.Format("15:04"),
+		"year": strconv.Itoa(now.Year()),
+		"user": os.Getenv("USER"),
+	}
+}
+
+var templateVar = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// expandTemplateVars подставляет переменные; неизвестные остаются как есть.
+func expandTemplateVars(s string, vars map[string]string) string {
+	return templateVar.ReplaceAllStringFunc(s, func(v string) string {
+		if value, ok := vars[templateVar.FindStringSubmatch(v)[1]]; ok {
+			return value
+		}
+		return v
+	})
+}
+
+// instantiateTemplate копирует шаблон src в dst, подставляя переменные в
+// имена вложенных элементов и в текстовые файлы. Двоичные файлы копируются
+// как есть. При ошибке частично созданный dst удаляется.
+func instantiateTemplate(src, dst string, vars map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		out := dst
+		if rel != "." {
+			out = filepath.Join(dst, expandTemplateVars(rel, vars))
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			return os.Mkdir(out, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(expandTemplateVars(link, vars), out)
+		case !info.Mode().IsRegular():
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if utf8.Valid(data) && !bytes.ContainsRune(data, 0) {
+			data = []byte(expandTemplateVars(string(data), vars))
+		}
+		f, err := os.OpenFile(out, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	})
+	if err != nil {
+		os.RemoveAll(dst)
+	}
+	return err
+}
+
+func (m model) renderCreate() string {
+	d := m.create
+	popupWidth := 64
+	popupStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("171")).
+		Padding(1, 2).
+		Width(popupWidth)
+	hint := lipgloss.NewStyle().Faint(true)
+
+	titles := map[createKind]string{
+		createFile:     "New file",
+		createDir:      "New directory",
+		createTemplate: "New from template",
+	}
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Render(titles[d.kind] + " in " + filepath.Base(d.dir)),
+		"",
+	}
+
+	if d.picking {
+		for i, e := range d.templates {
+			name := e.Name()
+			if e.IsDir() {
+				name += "/"
+			}
+			if i == d.cursor {
+				lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("171")).Bold(true).Render("● "+name))
+			} else {
+				lines = append(lines, "  "+name)
+			}
+		}
+		lines = append(lines, "", hint.Render("↑/↓ choose • Enter use • Esc cancel"))
+	} else {
+		if d.kind == createTemplate {
+			lines = append(lines, "Template: "+d.templates[d.cursor].Name())
+		}
+		lines = append(lines, d.input.View())
+		if d.err != "" {
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(d.err))
+		}
+		switch d.kind {
+		case createDir, createFile:
+			lines = append(lines, hint.Render("Missing parent directories (a/b/c) are created"))
+		case createTemplate:
+			lines = append(lines, hint.Render("Variables: {{name}} {{file}} {{dir}} {{date}} {{time}} {{year}} {{user}}"))
+		}
+		lines = append(lines, "", hint.Render("Enter create • Esc cancel"))
+	}
+
+	popup := popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	x := (m.width - popupWidth) / 2
+	y := (m.height - lipgloss.Height(popup)) / 2
+	if y < 0 {
+		y = 0
+	}
+	return lipgloss.NewStyle().MarginLeft(x).MarginTop(y).Render(popup)
+}
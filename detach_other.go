@@ -0,0 +1,14 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// startDetached запускает процесс, не дожидаясь его завершения.
+func startDetached(cmd *exec.Cmd) error {
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = nil, nil, nil
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// startDetached запускает процесс в отдельной сессии, чтобы он пережил выход из приложения.
+// Пока приложение работает, завершившийся процесс нужно дождаться,
+// иначе он останется зомби.
+func startDetached(cmd *exec.Cmd) error {
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = nil, nil, nil
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
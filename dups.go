@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Поиск одинаковых файлов. Кандидаты сначала делятся по размеру, затем по
+// хешу начала файла и только после этого по полному sha256 — так целиком
+// читаются лишь файлы, которые почти наверняка совпадают.
+
+// dupPartialSize — сколько байт от начала файла входит в частичный хеш.
+const dupPartialSize = 64 << 10
+
+// Что просматривать.
+const (
+	dupsActive = iota
+	dupsBoth
+	dupsScopeCount
+)
+
+// dupsDialog — выбор деревьев для поиска дубликатов.
+type dupsDialog struct {
+	dir        string
+	otherDir   string
+	showHidden bool
+	cursor     int
+}
+
+type dupGroup struct {
+	size  int64
+	files []string // пути относительно dupResults.root
+}
+
+// dupResults — группы одинаковых файлов, показанные в панели вместо
+// содержимого каталога. Элементы панели — пути относительно root, поэтому
+// выделение, удаление, ссылки и копирование работают с ними как обычно.
+type dupResults struct {
+	root   string
+	groups []dupGroup
+	group  map[string]int // путь → номер группы, с 1
+}
+
+func (m *model) openDups() {
+	dir, other, hidden := m.leftDir, m.rightDir, m.showHiddenLeft
+	if m.activePanel == 1 {
+		dir, other, hidden = m.rightDir, m.leftDir, m.showHiddenRight
+	}
+	if isArchiveDir(dir) {
+		m.termOutput.add("Duplicates: archives can't be scanned, open a regular directory.")
+		return
+	}
+	m.dupsDialog = &dupsDialog{dir: dir, otherDir: other, showHidden: hidden}
+}
+
+// scope возвращает общий корень и деревья для просмотра: вложенные друг
+// в друга каталоги просматриваются один раз.
+func (d *dupsDialog) scope(choice int) (root string, roots []string) {
+	switch {
+	case choice == dupsActive || isInside(d.otherDir, d.dir):
+		return d.dir, []string{d.dir}
+	case isInside(d.dir, d.otherDir):
+		return d.otherDir, []string{d.otherDir}
+	}
+	root = d.dir
+	for !isInside(d.otherDir, root) && filepath.Dir(root) != root {
+		root = filepath.Dir(root)
+	}
+	return root, []string{d.dir, d.otherDir}
+}
+
+func (d *dupsDialog) choiceLabel(choice int) string {
+	if choice == dupsActive {
+		return "This panel: " + d.dir
+	}
+	return "Both panels: " + d.dir + " and " + d.otherDir
+}
+
+func (m model) updateDupsDialog(msg tea.KeyMsg) (model, tea.Cmd) {
+	d := m.dupsDialog
+	switch msg.String() {
+	case "esc", "q":
+		m.dupsDialog = nil
+	case "up", "k":
+		if d.cursor > 0 {
+			d.cursor--
+		}
+	case "down", "j":
+		if d.cursor < dupsScopeCount-1 {
+			d.cursor++
+		}
+	case "enter":
+		if d.cursor == dupsBoth && isArchiveDir(d.otherDir) {
+			m.termOutput.add("Duplicates: the other panel is inside an archive.")
+			break
+		}
+		m.dupsDialog = nil
+		cmd := m.startDups(d, d.cursor)
+		return m, cmd
+	}
+	return m, nil
+}
+
+// startDups ищет дубликаты фоновым заданием и по его завершении
+// показывает группы в панели, которая была активной при запуске.
+func (m *model) startDups(d *dupsDialog, choice int) tea.Cmd {
+	panel := m.activePanel
+	root, roots := d.scope(choice)
+	var res *dupResults
+	title := "duplicates " + strings.Join(roots, " ")
+	return m.startTaskThen(title, func(ctx context.Context, out taskOutput) error {
+		var err error
+		res, err = findDuplicates(ctx, root, roots, d.showHidden, out)
+		return err
+	}, func(m *model) {
+		m.termOutput.add(res.summary())
+		if len(res.groups) == 0 {
+			return
+		}
+		if panel == 0 {
+			m.leftDir, m.leftDups = root, res
+			m.leftCursor, m.leftScroll = 0, 0
+			m.selectedLeft = make(map[string]bool)
+		} else {
+			m.rightDir, m.rightDups = root, res
+			m.rightCursor, m.rightScroll = 0, 0
+			m.selectedRight = make(map[string]bool)
+		}
+		m.reloadPanel(panel)
+	})
+}
+
+type dupFile struct {
+	path string
+	info fs.FileInfo
+}
+
+// findDuplicates просматривает деревья roots и возвращает группы файлов с
+// одинаковым содержимым. Пустые файлы, ссылки и жёсткие ссылки на один и
+// тот же файл дубликатами не считаются.
+func findDuplicates(ctx context.Context, root string, roots []string, showHidden bool, out taskOutput) (*dupResults, error) {
+	bySize := make(map[int64][]dupFile)
+	scanned := 0
+	for _, r := range roots {
+		err := filepath.WalkDir(r, func(p string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				out(err.Error(), true)
+				return nil
+			}
+			if p != r && !showHidden && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				out(err.Error(), true)
+				return nil
+			}
+			if info.Size() == 0 {
+				return nil
+			}
+			scanned++
+			for _, f := range bySize[info.Size()] {
+				if os.SameFile(f.info, info) {
+					return nil
+				}
+			}
+			bySize[info.Size()] = append(bySize[info.Size()], dupFile{p, info})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var candidates [][]dupFile
+	count := 0
+	for _, files := range bySize {
+		if len(files) > 1 {
+			candidates = append(candidates, files)
+			count += len(files)
+		}
+	}
+	out(fmt.Sprintf("Scanned %d files: %d candidates in %d size groups", scanned, count, len(candidates)), false)
+
+	// Частичный хеш, затем полный — только для совпавших по началу файлов
+	// длиннее dupPartialSize: у остальных частичный хеш уже полный.
+	candidates, err := splitByHash(ctx, candidates, partialHash, out)
+	if err != nil {
+		return nil, err
+	}
+	var final, large [][]dupFile
+	count = 0
+	for _, files := range candidates {
+		if files[0].info.Size() <= dupPartialSize {
+			final = append(final, files)
+		} else {
+			large = append(large, files)
+			count += len(files)
+		}
+	}
+	if len(large) > 0 {
+		out(fmt.Sprintf("Hashing %d large files completely", count), false)
+	}
+	large, err = splitByHash(ctx, large, fileHash, out)
+	if err != nil {
+		return nil, err
+	}
+	final = append(final, large...)
+
+	res := &dupResults{root: root}
+	for _, files := range final {
+		g := dupGroup{size: files[0].info.Size()}
+		for _, f := range files {
+			rel, err := filepath.Rel(root, f.path)
+			if err != nil {
+				return nil, err
+			}
+			g.files = append(g.files, rel)
+		}
+		sort.Strings(g.files)
+		res.groups = append(res.groups, g)
+	}
+	// Сначала группы, удаление дубликатов из которых освободит больше места
+	sort.Slice(res.groups, func(i, j int) bool {
+		a, b := res.groups[i], res.groups[j]
+		wa, wb := a.wasted(), b.wasted()
+		if wa != wb {
+			return wa > wb
+		}
+		return a.files[0] < b.files[0]
+	})
+	return res, nil
+}
+
+// splitByHash делит каждую группу по хешу и оставляет подгруппы хотя бы
+// из двух файлов.
+func splitByHash(ctx context.Context, groups [][]dupFile, hash func(string) (string, error), out taskOutput) ([][]dupFile, error) {
+	var result [][]dupFile
+	for _, files := range groups {
+		byHash := make(map[string][]dupFile)
+		var order []string
+		for _, f := range files {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			sum, err := hash(f.path)
+			if err != nil {
+				out(err.Error(), true)
+				continue
+			}
+			if _, ok := byHash[sum]; !ok {
+				order = append(order, sum)
+			}
+			byHash[sum] = append(byHash[sum], f)
+		}
+		for _, sum := range order {
+			if len(byHash[sum]) > 1 {
+				result = append(result, byHash[sum])
+			}
+		}
+	}
+	return result, nil
+}
+
+// partialHash — sha256 первых dupPartialSize байт файла.
+func partialHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, io.LimitReader(f, dupPartialSize)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// wasted — сколько места занимают копии сверх одной.
+func (g dupGroup) wasted() int64 {
+	return g.size * int64(len(g.files)-1)
+}
+
+func (r *dupResults) summary() string {
+	if len(r.groups) == 0 {
+		return "Duplicates: none found."
+	}
+	files := 0
+	var wasted int64
+	for _, g := range r.groups {
+		files += len(g.files) - 1
+		wasted += g.wasted()
+	}
+	return fmt.Sprintf("Duplicates: %d groups, %d redundant files, %s reclaimable. < / > select all but the oldest / newest, Esc closes.",
+		len(r.groups), files, humanSize(wasted))
+}
+
+// items перечитывает группы для панели: исчезнувшие файлы выпадают,
+// а группы, где не осталось копий, исчезают целиком.
+func (r *dupResults) items() []string {
+	kept := r.groups[:0]
+	for _, g := range r.groups {
+		files := g.files[:0]
+		for _, f := range g.files {
+			if info, err := os.Lstat(filepath.Join(r.root, f)); err == nil && info.Mode().IsRegular() {
+				files = append(files, f)
+			}
+		}
+		g.files = files
+		if len(files) > 1 {
+			kept = append(kept, g)
+		}
+	}
+	r.groups = kept
+
+	r.group = make(map[string]int)
+	var items []string
+	for i, g := range r.groups {
+		for _, f := range g.files {
+			r.group[f] = i + 1
+			items = append(items, f)
+		}
+	}
+	return items
+}
+
+// activeDups — результаты поиска дубликатов в активной панели или nil.
+func (m model) activeDups() *dupResults {
+	if m.activePanel == 1 {
+		return m.rightDups
+	}
+	return m.leftDups
+}
+
+// closeDups возвращает активной панели обычное содержимое каталога.
+func (m *model) closeDups() {
+	if m.activeDups() == nil {
+		return
+	}
+	if m.activePanel == 0 {
+		m.leftDups = nil
+	} else {
+		m.rightDups = nil
+	}
+	m.reloadPanel(m.activePanel)
+	m.adjustScroll()
+}
+
+// selectDuplicates выделяет в каждой группе все файлы, кроме самого нового
+// (keepNewest) или самого старого; при равном mtime остаётся первый по имени.
+// Файлы, которые не удалось прочитать, не выделяются, а группа, где
+// оставить нечего, пропускается целиком.
+func (m *model) selectDuplicates(keepNewest bool) {
+	r := m.activeDups()
+	if r == nil {
+		m.termOutput.add("Not a duplicates panel: press U to search for duplicates first.")
+		return
+	}
+	selected := make(map[string]bool)
+	for _, g := range r.groups {
+		keep := -1
+		var keepInfo fs.FileInfo
+		readable := make([]bool, len(g.files))
+		for i, f := range g.files {
+			info, err := os.Stat(filepath.Join(r.root, f))
+			if err != nil {
+				continue
+			}
+			readable[i] = true
+			if keep < 0 || keepNewest && info.ModTime().After(keepInfo.ModTime()) ||
+				!keepNewest && info.ModTime().Before(keepInfo.ModTime()) {
+				keep, keepInfo = i, info
+			}
+		}
+		if keep < 0 {
+			continue
+		}
+		for i, f := range g.files {
+			if i != keep && readable[i] {
+				selected[f] = true
+			}
+		}
+	}
+	if m.activePanel == 0 {
+		m.selectedLeft = selected
+	} else {
+		m.selectedRight = selected
+	}
+	which := "oldest"
+	if keepNewest {
+		which = "newest"
+	}
+	m.termOutput.add(fmt.Sprintf("Selected %d duplicates, keeping the %s file of each group.", len(selected), which))
+}
+
+// dupTag — номер группы перед именем; соседние группы различаются цветом.
+func (r *dupResults) dupTag(item string) string {
+	g := r.group[item]
+	width := len(strconv.Itoa(len(r.groups)))
+	color := "39"
+	if g%2 == 0 {
+		color = "214"
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render(fmt.Sprintf("%*d ", width, g))
+}
+
+// note — пояснение к заголовку панели с результатами.
+func (r *dupResults) note() string {
+	var wasted int64
+	for _, g := range r.groups {
+		wasted += g.wasted()
+	}
+	return fmt.Sprintf(" (duplicates: %d groups, %s reclaimable)", len(r.groups), humanSize(wasted))
+}
+
+func (m model) renderDupsDialog() string {
+	d := m.dupsDialog
+	popupWidth := 70
+	popupStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("171")).
+		Padding(1, 2).
+		Width(popupWidth)
+
+	lines := []string{lipgloss.NewStyle().Bold(true).Render("Find duplicate files"), ""}
+	for i := 0; i < dupsScopeCount; i++ {
+		if i == d.cursor {
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("171")).Bold(true).Render("● "+d.choiceLabel(i)))
+		} else {
+			lines = append(lines, "  "+d.choiceLabel(i))
+		}
+	}
+	lines = append(lines, "", lipgloss.NewStyle().Faint(true).Render("↑/↓ choose • Enter search • Esc cancel"))
+
+	popup := popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	x := (m.width - popupWidth) / 2
+	y := (m.height - lipgloss.Height(popup)) / 2
+	if y < 0 {
+		y = 0
+	}
+	return lipgloss.NewStyle().MarginLeft(x).MarginTop(y).Render(popup)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func discardOutput(string, bool) {}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, body := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.txt":       "same",
+		"sub/b.txt":   "same",
+		"c.txt":       "diff", // тот же размер, другое содержимое
+		"empty1":      "",
+		"empty2":      "",
+		".hidden/d":   "same",
+		"big/one.bin": "longer content",
+		"big/two.bin": "longer content",
+		"lonely.txt":  "unique!",
+	})
+	if err := os.Link(filepath.Join(root, "a.txt"), filepath.Join(root, "hard.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(root, "sym.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := findDuplicates(context.Background(), root, []string{root}, false, discardOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for _, g := range res.groups {
+		got = append(got, g.files)
+	}
+	// Жёсткая и символьная ссылки копиями не считаются, скрытые файлы пропущены.
+	// Группы идут по убыванию занятого копиями места.
+	want := [][]string{
+		{"big/one.bin", "big/two.bin"},
+		{"a.txt", "sub/b.txt"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %q, want %q", got, want)
+	}
+
+	res, err = findDuplicates(context.Background(), root, []string{root}, true, discardOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, g := range res.groups {
+		for _, f := range g.files {
+			found = found || f == filepath.Join(".hidden", "d")
+		}
+	}
+	if !found {
+		t.Error("hidden duplicate not found with showHidden")
+	}
+}
+
+func TestSplitByHash(t *testing.T) {
+	sums := map[string]string{"a": "1", "b": "2", "c": "1", "d": "2", "e": "3", "f": "1"}
+	hash := func(p string) (string, error) {
+		if p == "bad" {
+			return "", errors.New("unreadable")
+		}
+		return sums[p], nil
+	}
+	files := func(names ...string) []dupFile {
+		var out []dupFile
+		for _, n := range names {
+			out = append(out, dupFile{path: n})
+		}
+		return out
+	}
+	var errs []string
+	out := func(line string, isErr bool) {
+		if isErr {
+			errs = append(errs, line)
+		}
+	}
+
+	got, err := splitByHash(context.Background(), [][]dupFile{
+		files("a", "b", "c", "bad", "d", "e"),
+		files("f", "e"),
+	}, hash, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]dupFile{files("a", "c"), files("b", "d")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitByHash = %v, want %v", got, want)
+	}
+	if len(errs) != 1 {
+		t.Errorf("hash errors reported %d times, want 1", len(errs))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := splitByHash(ctx, [][]dupFile{files("a", "c")}, hash, out); err == nil {
+		t.Error("cancelled context must stop hashing")
+	}
+}
+
+func TestDupsScope(t *testing.T) {
+	tests := []struct {
+		dir, other string
+		choice     int
+		root       string
+		roots      []string
+	}{
+		{"/a/b", "/c", dupsActive, "/a/b", []string{"/a/b"}},
+		{"/a/b", "/a/b/c", dupsBoth, "/a/b", []string{"/a/b"}},
+		{"/a/b/c", "/a/b", dupsBoth, "/a/b", []string{"/a/b"}},
+		{"/a/b/c", "/a/b/d", dupsBoth, "/a/b", []string{"/a/b/c", "/a/b/d"}},
+		{"/x/y", "/z", dupsBoth, "/", []string{"/x/y", "/z"}},
+		{"/a/bc", "/a/b", dupsBoth, "/a", []string{"/a/bc", "/a/b"}},
+	}
+	for _, tt := range tests {
+		d := &dupsDialog{dir: tt.dir, otherDir: tt.other}
+		root, roots := d.scope(tt.choice)
+		if root != tt.root || !reflect.DeepEqual(roots, tt.roots) {
+			t.Errorf("scope(%q, %q, %d) = %q, %q, want %q, %q",
+				tt.dir, tt.other, tt.choice, root, roots, tt.root, tt.roots)
+		}
+	}
+}
+
+func TestSelectDuplicates(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"old": "x", "mid": "x", "new": "x", "solo": "y", "gone2": "y"})
+	now := time.Now()
+	for name, age := range map[string]time.Duration{"old": 3 * time.Hour, "mid": 2 * time.Hour, "new": time.Hour} {
+		if err := os.Chtimes(filepath.Join(root, name), now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res := &dupResults{root: root, groups: []dupGroup{
+		{size: 1, files: []string{"mid", "new", "old", "vanished"}},
+		{size: 1, files: []string{"gone1", "gone2"}},
+		{size: 1, files: []string{"missing1", "missing2"}},
+	}}
+	os.Remove(filepath.Join(root, "gone2"))
+
+	m := model{leftDups: res, termOutput: newScrollback(100)}
+	m.selectDuplicates(false)
+	if want := map[string]bool{"mid": true, "new": true}; !reflect.DeepEqual(m.selectedLeft, want) {
+		t.Errorf("keep oldest selected %v, want %v", m.selectedLeft, want)
+	}
+	m.selectDuplicates(true)
+	if want := map[string]bool{"mid": true, "old": true}; !reflect.DeepEqual(m.selectedLeft, want) {
+		t.Errorf("keep newest selected %v, want %v", m.selectedLeft, want)
+	}
+}
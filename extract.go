@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Куда распаковывать.
+const (
+	extractOther = iota
+	extractOtherSub
+	extractHere
+	extractHereSub
+	extractTargetCount
+)
+
+// extractDialog — выбор места распаковки архивов из выделения.
+type extractDialog struct {
+	dir      string
+	otherDir string
+	archives []string
+	cursor   int
+}
+
+func (m *model) openExtract() {
+	dir := m.activeDir()
+	if isArchiveDir(dir) {
+		m.termOutput.add("Inside an archive: use c and p to copy entries out.")
+		return
+	}
+	names := m.activeSelection()
+	if len(names) == 0 {
+		items, cursor := m.activeItems(), m.leftCursor
+		if m.activePanel == 1 {
+			cursor = m.rightCursor
+		}
+		if len(items) == 0 {
+			return
+		}
+		names = []string{items[cursor]}
+	}
+	d := &extractDialog{dir: dir, otherDir: m.rightDir}
+	if m.activePanel == 1 {
+		d.otherDir = m.leftDir
+	}
+	for _, name := range names {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil || !info.Mode().IsRegular() || formatByName(name) < 0 {
+			m.termOutput.add("Extract: skipping " + name + ", not a supported archive")
+			continue
+		}
+		d.archives = append(d.archives, name)
+	}
+	if len(d.archives) > 0 {
+		m.extract = d
+	}
+}
+
+// target — каталог распаковки архива name для варианта choice.
+func (d *extractDialog) target(choice int, name string) string {
+	switch choice {
+	case extractOther:
+		return d.otherDir
+	case extractOtherSub:
+		return filepath.Join(d.otherDir, stripArchiveExt(name))
+	case extractHere:
+		return d.dir
+	}
+	return filepath.Join(d.dir, stripArchiveExt(name))
+}
+
+func (d *extractDialog) choiceLabel(choice int) string {
+	sub := "<archive name>/"
+	if len(d.archives) == 1 {
+		sub = stripArchiveExt(d.archives[0]) + "/"
+	}
+	switch choice {
+	case extractOther:
+		return "Other panel: " + d.otherDir
+	case extractOtherSub:
+		return "Subfolder in other panel: " + filepath.Join(d.otherDir, sub)
+	case extractHere:
+		return "Here: " + d.dir
+	}
+	return "Subfolder here: " + filepath.Join(d.dir, sub)
+}
+
+func (m model) updateExtract(msg tea.KeyMsg) (model, tea.Cmd) {
+	d := m.extract
+	switch msg.String() {
+	case "esc", "q":
+		m.extract = nil
+	case "up", "k":
+		if d.cursor > 0 {
+			d.cursor--
+		}
+	case "down", "j":
+		if d.cursor < extractTargetCount-1 {
+			d.cursor++
+		}
+	case "enter":
+		m.extract = nil
+		cmd := m.startExtract(d, d.cursor)
+		return m, cmd
+	}
+	return m, nil
+}
+
+// startExtract считает конфликты по оглавлениям и, как при копировании,
+// спрашивает о перезаписи существующих файлов.
+func (m *model) startExtract(d *extractDialog, choice int) tea.Cmd {
+	if choice <= extractOtherSub && isArchiveDir(d.otherDir) {
+		m.termOutput.add("Extract: the other panel is inside a read-only archive.")
+		return nil
+	}
+	existing := 0
+	for _, name := range d.archives {
+		idx, err := openArchiveIndex(filepath.Join(d.dir, name))
+		if err != nil {
+			m.termOutput.add(fmt.Sprintf("Extract: %s: %v", name, err))
+			return nil
+		}
+		dst := d.target(choice, name)
+		for entry, e := range idx.entries {
+			if e.info.IsDir() {
+				continue
+			}
+			if _, err := os.Lstat(filepath.Join(dst, filepath.FromSlash(entry))); err == nil {
+				existing++
+			}
+		}
+	}
+
+	return m.confirmOverwrite("extract", existing, false, func(m *model) tea.Cmd {
+		// Заменяем файлы, только если пользователь это подтвердил; появившиеся
+		// после проверки файлы пропускаются
+		overwrite := existing > 0
+		type job struct{ archive, dst string }
+		var jobs []job
+		for _, name := range d.archives {
+			jobs = append(jobs, job{filepath.Join(d.dir, name), d.target(choice, name)})
+		}
+		title := "extract " + strings.Join(d.archives, " ")
+		return m.startTask(title, func(ctx context.Context, out taskOutput) error {
+			failed := 0
+			for _, j := range jobs {
+				if len(jobs) > 1 {
+					out(filepath.Base(j.archive)+" → "+j.dst, false)
+				}
+				if err := extractArchive(ctx, j.archive, "", j.dst, overwrite, out); err != nil {
+					if ctx.Err() != nil {
+						return err
+					}
+					failed++
+					out(filepath.Base(j.archive)+": "+err.Error(), true)
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d archives failed", failed, len(jobs))
+			}
+			return nil
+		})
+	})
+}
+
+func (m model) renderExtract() string {
+	d := m.extract
+	popupWidth := 70
+	popupStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("171")).
+		Padding(1, 2).
+		Width(popupWidth)
+
+	what := d.archives[0]
+	if len(d.archives) > 1 {
+		what = fmt.Sprintf("%d archives", len(d.archives))
+	}
+	lines := []string{lipgloss.NewStyle().Bold(true).Render("Extract " + what), ""}
+	for i := 0; i < extractTargetCount; i++ {
+		if i == d.cursor {
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("171")).Bold(true).Render("● "+d.choiceLabel(i)))
+		} else {
+			lines = append(lines, "  "+d.choiceLabel(i))
+		}
+	}
+	lines = append(lines, "", lipgloss.NewStyle().Faint(true).Render("↑/↓ choose • Enter extract • Esc cancel"))
+
+	popup := popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	x := (m.width - popupWidth) / 2
+	y := (m.height - lipgloss.Height(popup)) / 2
+	if y < 0 {
+		y = 0
+	}
+	return lipgloss.NewStyle().MarginLeft(x).MarginTop(y).Render(popup)
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// humanSize форматирует размер как du -h: 512, 4.0K, 12M.
+func humanSize(n int64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return fmt.Sprintf("%d", n)
+	}
+	v := float64(n)
+	i := -1
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	if v < 10 {
+		return fmt.Sprintf("%.1f%c", v, units[i])
+	}
+	return fmt.Sprintf("%.0f%c", v, units[i])
+}
+
+// isInside проверяет, что path совпадает с dir или лежит внутри него.
+func isInside(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+func builtinMkdir(m *model, args []string) tea.Cmd {
+	flags, paths, err := parseFlags("mkdir", args[1:], "p")
+	if err != nil || len(paths) == 0 {
+		m.termOutput.add(errOrUsage(err, "usage: mkdir [-p] dir..."))
+		return nil
+	}
+	for _, p := range paths {
+		path := resolvePath(m.activeDir(), p)
+		if flags['p'] {
+			err = os.MkdirAll(path, 0755)
+		} else {
+			err = os.Mkdir(path, 0755)
+		}
+		if err != nil {
+			m.termOutput.add("mkdir: " + err.Error())
+			continue
+		}
+		m.refreshPanelsAfterChange(filepath.Dir(path))
+	}
+	return nil
+}
+
+func builtinTouch(m *model, args []string) tea.Cmd {
+	_, paths, err := parseFlags("touch", args[1:], "")
+	if err != nil || len(paths) == 0 {
+		m.termOutput.add(errOrUsage(err, "usage: touch file..."))
+		return nil
+	}
+	now := time.Now()
+	for _, p := range paths {
+		path := resolvePath(m.activeDir(), p)
+		if _, err := os.Stat(path); err == nil {
+			if err := os.Chtimes(path, now, now); err != nil {
+				m.termOutput.add("touch: " + err.Error())
+			}
+			continue
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			m.termOutput.add("touch: " + err.Error())
+			continue
+		}
+		f.Close()
+		m.refreshPanelsAfterChange(filepath.Dir(path))
+	}
+	return nil
+}
+
+func errOrUsage(err error, usage string) string {
+	if err != nil {
+		return err.Error()
+	}
+	return usage
+}
+
+// transferPair — источник и итоговый путь для cp и mv.
+type transferPair struct {
+	src, dst string
+}
+
+// planTransfer разбирает "src... dst" как cp и mv: при нескольких
+// источниках или существующем каталоге dst файлы кладутся внутрь него.
+// Возвращает пары и число уже существующих целей.
+func (m *model) planTransfer(name string, operands []string, recursive bool) ([]transferPair, int) {
+	if len(operands) < 2 {
+		m.termOutput.add(fmt.Sprintf("usage: %s [options] source... dest", name))
+		return nil, 0
+	}
+	dir := m.activeDir()
+	dst := resolvePath(dir, operands[len(operands)-1])
+	fi, err := os.Stat(dst)
+	dstIsDir := err == nil && fi.IsDir()
+	if len(operands) > 2 && !dstIsDir {
+		m.termOutput.add(fmt.Sprintf("%s: target %s is not a directory", name, dst))
+		return nil, 0
+	}
+
+	var pairs []transferPair
+	existing := 0
+	for _, op := range operands[:len(operands)-1] {
+		src := resolvePath(dir, op)
+		info, err := os.Lstat(src)
+		if err != nil {
+			m.termOutput.add(name + ": " + err.Error())
+			continue
+		}
+		if info.IsDir() && !recursive {
+			m.termOutput.add(fmt.Sprintf("%s: -r not specified; omitting directory %s", name, op))
+			continue
+		}
+		target := dst
+		if dstIsDir {
+			target = filepath.Join(dst, filepath.Base(src))
+		}
+		if target == src || (info.IsDir() && isInside(target, src)) {
+			m.termOutput.add(fmt.Sprintf("%s: cannot %s %s into itself", name, name, op))
+			continue
+		}
+		if _, err := os.Lstat(target); err == nil {
+			existing++
+		}
+		pairs = append(pairs, transferPair{src, target})
+	}
+	return pairs, existing
+}
+
+// confirmOverwrite выполняет do сразу или после подтверждения, если
+// какие-то цели уже существуют.
+func (m *model) confirmOverwrite(name string, existing int, force bool, do func(m *model) tea.Cmd) tea.Cmd {
+	if existing == 0 || force {
+		return do(m)
+	}
+	m.confirm = &confirmDialog{
+		prompt: fmt.Sprintf("%s: overwrite %d existing item(s)?", name, existing),
+		onYes:  do,
+	}
+	return nil
+}
+
+// cp копирует тем же движком, что и клавиша p: каждая пара — отдельное
+// фоновое копирование с copyDoneMsg по завершении.
+func builtinCp(m *model, args []string) tea.Cmd {
+	flags, operands, err := parseFlags("cp", args[1:], "rRf")
+	if err != nil {
+		m.termOutput.add(err.Error())
+		return nil
+	}
+	pairs, existing := m.planTransfer("cp", operands, flags['r'] || flags['R'])
+	if len(pairs) == 0 {
+		return nil
+	}
+	return m.confirmOverwrite("cp", existing, flags['f'], func(m *model) tea.Cmd {
+		var cmds []tea.Cmd
+		for _, p := range pairs {
+			m.termOutput.add(fmt.Sprintf("Started copying %s → %s", filepath.Base(p.src), filepath.Dir(p.dst)))
+			cmds = append(cmds, copyFileAsync(context.Background(), p.src, p.dst))
+		}
+		return tea.Batch(cmds...)
+	})
+}
+
+func builtinMv(m *model, args []string) tea.Cmd {
+	flags, operands, err := parseFlags("mv", args[1:], "f")
+	if err != nil {
+		m.termOutput.add(err.Error())
+		return nil
+	}
+	pairs, existing := m.planTransfer("mv", operands, true)
+	if len(pairs) == 0 {
+		return nil
+	}
+	return m.confirmOverwrite("mv", existing, flags['f'], func(m *model) tea.Cmd {
+		for _, p := range pairs {
+			if err := moveFile(p.src, p.dst); err != nil {
+				m.termOutput.add("mv: " + err.Error())
+				continue
+			}
+			m.termOutput.add("Moved to: " + p.dst)
+			m.refreshPanelsAfterChange(filepath.Dir(p.src))
+			m.refreshPanelsAfterChange(filepath.Dir(p.dst))
+		}
+		return nil
+	})
+}
+
+// rm не удаляет, а переносит в корзину; без -f спрашивает подтверждение.
+func builtinRm(m *model, args []string) tea.Cmd {
+	flags, operands, err := parseFlags("rm", args[1:], "rRf")
+	if err != nil || len(operands) == 0 {
+		m.termOutput.add(errOrUsage(err, "usage: rm [-r] [-f] path..."))
+		return nil
+	}
+	var targets []string
+	for _, op := range operands {
+		path := resolvePath(m.activeDir(), op)
+		info, err := os.Lstat(path)
+		if err != nil {
+			if !flags['f'] {
+				m.termOutput.add("rm: " + err.Error())
+			}
+			continue
+		}
+		if info.IsDir() && !flags['r'] && !flags['R'] {
+			m.termOutput.add("rm: " + op + ": is a directory (use -r)")
+			continue
+		}
+		targets = append(targets, path)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	do := func(m *model) tea.Cmd {
+		for _, t := range targets {
+			if err := moveToTrash(t); err != nil {
+				m.termOutput.add("rm: " + err.Error())
+				continue
+			}
+			m.termOutput.add("Trashed: " + t)
+			m.refreshPanelsAfterChange(filepath.Dir(t))
+		}
+		m.adjustScroll()
+		return nil
+	}
+	if flags['f'] {
+		return do(m)
+	}
+	prompt := fmt.Sprintf("Move %d item(s) to trash?", len(targets))
+	if len(targets) == 1 {
+		prompt = "Move " + filepath.Base(targets[0]) + " to trash?"
+	}
+	m.confirm = &confirmDialog{prompt: prompt, onYes: do}
+	return nil
+}
+
+// find [path...] [-name glob] [-iname glob] [-type f|d] — обход в фоне.
+func builtinFind(m *model, args []string) tea.Cmd {
+	var roots []string
+	var name, iname, typ string
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		if !strings.HasPrefix(a, "-") {
+			roots = append(roots, a)
+			continue
+		}
+		if i+1 >= len(args) {
+			m.termOutput.add("find: missing argument to " + a)
+			return nil
+		}
+		i++
+		switch a {
+		case "-name":
+			name = args[i]
+		case "-iname":
+			iname = strings.ToLower(args[i])
+		case "-type":
+			typ = args[i]
+			if typ != "f" && typ != "d" {
+				m.termOutput.add("find: -type must be f or d")
+				return nil
+			}
+		default:
+			m.termOutput.add("find: unknown predicate " + a)
+			return nil
+		}
+	}
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	dir := m.activeDir()
+
+	return func() tea.Msg {
+		var out []string
+		for _, root := range roots {
+			base := resolvePath(dir, root)
+			err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					out = append(out, stderrStyle.Render("find: "+err.Error()))
+					return nil
+				}
+				if typ == "f" && d.IsDir() || typ == "d" && !d.IsDir() {
+					return nil
+				}
+				if name != "" {
+					if ok, _ := filepath.Match(name, d.Name()); !ok {
+						return nil
+					}
+				}
+				if iname != "" {
+					if ok, _ := filepath.Match(iname, strings.ToLower(d.Name())); !ok {
+						return nil
+					}
+				}
+				// Пути печатаются относительно указанного корня, как в find
+				rel, _ := filepath.Rel(base, path)
+				out = append(out, filepath.Join(root, rel))
+				return nil
+			})
+			if err != nil {
+				out = append(out, stderrStyle.Render("find: "+err.Error()))
+			}
+		}
+		return builtinOutputMsg{Lines: out}
+	}
+}
+
+// du [-s] [-h] [path...] — суммарный размер файлов, считается в фоне.
+func builtinDu(m *model, args []string) tea.Cmd {
+	flags, paths, err := parseFlags("du", args[1:], "sh")
+	if err != nil {
+		m.termOutput.add(err.Error())
+		return nil
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	dir := m.activeDir()
+	format := func(n int64) string {
+		if flags['h'] {
+			return humanSize(n)
+		}
+		// Как du без -h — в килобайтах
+		return fmt.Sprintf("%d", (n+1023)/1024)
+	}
+
+	return func() tea.Msg {
+		var out []string
+		for _, p := range paths {
+			total := duWalk(resolvePath(dir, p), p, func(path string, size int64) {
+				if !flags['s'] {
+					out = append(out, format(size)+"\t"+path)
+				}
+			}, &out)
+			if flags['s'] {
+				out = append(out, format(total)+"\t"+p)
+			}
+		}
+		return builtinOutputMsg{Lines: out}
+	}
+}
+
+// duWalk считает размер path; report вызывается для каждого каталога после
+// его содержимого. Ошибки дописываются в errs.
+func duWalk(path, shown string, report func(string, int64), errs *[]string) int64 {
+	info, err := os.Lstat(path)
+	if err != nil {
+		*errs = append(*errs, stderrStyle.Render("du: "+err.Error()))
+		return 0
+	}
+	if !info.IsDir() {
+		return info.Size()
+	}
+	var total int64
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		*errs = append(*errs, stderrStyle.Render("du: "+err.Error()))
+	}
+	for _, e := range entries {
+		total += duWalk(filepath.Join(path, e.Name()), filepath.Join(shown, e.Name()), report, errs)
+	}
+	report(shown, total)
+	return total
+}
+
+// ln [-s] [-f] target [link] — ссылка в каталоге активной панели.
+func builtinLn(m *model, args []string) tea.Cmd {
+	flags, operands, err := parseFlags("ln", args[1:], "sf")
+	if err != nil || len(operands) == 0 || len(operands) > 2 {
+		m.termOutput.add(errOrUsage(err, "usage: ln [-s] [-f] target [link]"))
+		return nil
+	}
+	dir := m.activeDir()
+	target := operands[0]
+	link := filepath.Join(dir, filepath.Base(target))
+	if len(operands) == 2 {
+		link = resolvePath(dir, operands[1])
+		if fi, err := os.Stat(link); err == nil && fi.IsDir() {
+			link = filepath.Join(link, filepath.Base(target))
+		}
+	}
+	if flags['f'] {
+		os.Remove(link)
+	}
+	if flags['s'] {
+		// Цель символической ссылки сохраняется как есть, относительно ссылки
+		err = os.Symlink(target, link)
+	} else {
+		err = os.Link(resolvePath(dir, target), link)
+	}
+	if err != nil {
+		m.termOutput.add("ln: " + err.Error())
+		return nil
+	}
+	m.refreshPanelsAfterChange(filepath.Dir(link))
+	return nil
+}
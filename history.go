@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const maxHistory = 1000
+
+// historyEntry — одна выполненная команда и каталог, в котором её запускали.
+type historyEntry struct {
+	Cmd  string `json:"cmd"`
+	Dir  string `json:"dir"`
+	Time int64  `json:"time"`
+}
+
+// commandHistory — история команд терминала, хранится в XDG state.
+type commandHistory struct {
+	path    string
+	entries []historyEntry // от старых к новым
+
+	// навигация стрелками: pos — индекс в view(), len(view) — «сейчас»
+	pos     int
+	draft   string
+	dirOnly bool // показывать только команды из каталога активной панели
+
+	search *historySearch
+}
+
+// historySearch — состояние обратного инкрементального поиска (ctrl+r).
+type historySearch struct {
+	query string
+	skip  int // сколько совпадений пропустить (повторный ctrl+r)
+	match string
+	draft string
+}
+
+func historyPath() string {
+	return filepath.Join(xdgDir("XDG_STATE_HOME", ".local/state"), "nddtc2", "history")
+}
+
+// loadHistory читает историю; повреждённые строки пропускаются.
+func loadHistory(path string) *commandHistory {
+	h := &commandHistory{path: path, pos: -1}
+	f, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var e historyEntry
+		if json.Unmarshal(sc.Bytes(), &e) == nil && e.Cmd != "" {
+			h.entries = append(h.entries, e)
+		}
+	}
+	return h
+}
+
+func (h *commandHistory) save() error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return err
+	}
+	tmp := h.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, e := range h.entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.path)
+}
+
+// add запоминает команду; повтор той же команды в том же каталоге
+// переносится в конец вместо дублирования.
+func (h *commandHistory) add(cmd, dir string) error {
+	kept := h.entries[:0]
+	for _, e := range h.entries {
+		if e.Cmd != cmd || e.Dir != dir {
+			kept = append(kept, e)
+		}
+	}
+	h.entries = append(kept, historyEntry{Cmd: cmd, Dir: dir, Time: time.Now().Unix()})
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	h.reset()
+	return h.save()
+}
+
+// view — команды для навигации (от старых к новым) без повторов текста.
+func (h *commandHistory) view(dir string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		e := h.entries[i]
+		if (h.dirOnly && e.Dir != dir) || seen[e.Cmd] {
+			continue
+		}
+		seen[e.Cmd] = true
+		out = append(out, e.Cmd)
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// reset возвращает навигацию в положение «после последней команды».
+func (h *commandHistory) reset() {
+	h.pos = -1
+	h.draft = ""
+}
+
+// prev возвращает более старую команду; current — текущий ввод (сохраняется как черновик).
+func (h *commandHistory) prev(current, dir string) (string, bool) {
+	list := h.view(dir)
+	if h.pos < 0 {
+		h.pos = len(list)
+		h.draft = current
+	}
+	if h.pos == 0 {
+		return "", false
+	}
+	h.pos--
+	return list[h.pos], true
+}
+
+// next возвращает более новую команду или черновик.
+func (h *commandHistory) next(dir string) (string, bool) {
+	if h.pos < 0 {
+		return "", false
+	}
+	list := h.view(dir)
+	h.pos++
+	if h.pos >= len(list) {
+		draft := h.draft
+		h.reset()
+		return draft, true
+	}
+	return list[h.pos], true
+}
+
+// find ищет skip-е с конца совпадение с подстрокой query.
+func (h *commandHistory) find(query, dir string, skip int) (string, bool) {
+	list := h.view(dir)
+	for i := len(list) - 1; i >= 0; i-- {
+		if strings.Contains(list[i], query) {
+			if skip == 0 {
+				return list[i], true
+			}
+			skip--
+		}
+	}
+	return "", false
+}
+
+// updateSearchKey обрабатывает клавиши в режиме ctrl+r. Возвращает false для
+// Enter: найденная команда уже в поле ввода и выполняется как обычно.
+func (h *commandHistory) updateSearchKey(m *model, msg tea.KeyMsg) bool {
+	s := h.search
+	dir := m.activeDir()
+
+	refresh := func() bool {
+		match, ok := h.find(s.query, dir, s.skip)
+		if ok {
+			s.match = match
+		}
+		return ok
+	}
+
+	switch msg.String() {
+	case "ctrl+r":
+		s.skip++
+		if !refresh() {
+			s.skip--
+		}
+	case "backspace":
+		if r := []rune(s.query); len(r) > 0 {
+			s.query = string(r[:len(r)-1])
+		}
+		s.skip = 0
+		s.match = ""
+		refresh()
+	case "esc", "ctrl+g":
+		m.termInput.SetValue(s.draft)
+		h.search = nil
+	case "enter":
+		if s.match != "" {
+			m.termInput.SetValue(s.match)
+		}
+		h.search = nil
+		return false
+	case "left", "right", "home", "end", "up", "down":
+		if s.match != "" {
+			m.termInput.SetValue(s.match)
+			m.termInput.CursorEnd()
+		}
+		h.search = nil
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			s.query += string(msg.Runes)
+			s.skip = 0
+			refresh()
+		}
+	}
+	return true
+}
+
+// searchPrompt — строка ввода в режиме поиска по истории.
+func (h *commandHistory) searchPrompt() string {
+	return "(reverse-i-search)`" + h.search.query + "': " + h.search.match
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var jobStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+
+// startJob запускает конвейер фоновым заданием. Фоновые задания не
+// ограничены по времени: это плееры, GUI-программы и прочие долгие процессы.
+func (m *model) startJob(p *pipeline, workingDir string) tea.Cmd {
+	run, cmd := runCommandAsync(p, workingDir, 0)
+	run.Job = m.nextJobNumber()
+	m.jobs = append(m.jobs, run)
+	m.termOutput.add(fmt.Sprintf("[%d] %s", run.Job, run.Command))
+	return cmd
+}
+
+// taskOutput передаёт строку вывода внутреннего задания.
+type taskOutput func(line string, isErr bool)
+
+// startTask запускает долгую работу самого приложения фоновым заданием:
+// оно видно в jobs, прерывается kill %N, а вывод идёт как у команд.
+// Ошибка work становится состоянием задания в строке завершения.
+func (m *model) startTask(title string, work func(ctx context.Context, out taskOutput) error) tea.Cmd {
+	return m.startTaskThen(title, work, nil)
+}
+
+// startTaskThen — startTask, после успешного завершения которого then
+// получает модель в Update: так задание передаёт результат интерфейсу.
+func (m *model) startTaskThen(title string, work func(ctx context.Context, out taskOutput) error, then func(m *model)) tea.Cmd {
+	nextCommandID++
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &commandRun{
+		ID:      nextCommandID,
+		Job:     m.nextJobNumber(),
+		Command: title,
+		Started: time.Now(),
+		cancel:  cancel,
+		events:  make(chan tea.Msg, 256),
+		then:    then,
+	}
+	m.jobs = append(m.jobs, run)
+	m.termOutput.add(fmt.Sprintf("[%d] %s", run.Job, run.Command))
+
+	go func() {
+		defer close(run.events)
+		defer cancel()
+		err := work(ctx, func(line string, isErr bool) {
+			run.events <- commandOutputMsg{ID: run.ID, Line: line, Stderr: isErr}
+		})
+		code := 0
+		switch {
+		case ctx.Err() != nil:
+			err = errCancelled
+		case err != nil:
+			code = 1
+		}
+		run.events <- commandExitMsg{ID: run.ID, Code: code, Duration: time.Since(run.Started), Error: err}
+	}()
+	return run.wait()
+}
+
+// nextJobNumber — номер для нового задания; нумерация начинается заново,
+// когда все задания завершились.
+func (m model) nextJobNumber() int {
+	n := 0
+	for _, j := range m.jobs {
+		n = max(n, j.Job)
+	}
+	return n + 1
+}
+
+// findRun ищет команду по ID среди переднего плана и фоновых заданий.
+func (m model) findRun(id int) *commandRun {
+	if m.running != nil && m.running.ID == id {
+		return m.running
+	}
+	for _, j := range m.jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// findJob разбирает ссылку на задание: "%N", "N" или пусто — последнее.
+func (m model) findJob(spec string) (*commandRun, error) {
+	if len(m.jobs) == 0 {
+		return nil, fmt.Errorf("no current job")
+	}
+	if spec == "" || spec == "%" || spec == "%+" {
+		return m.jobs[len(m.jobs)-1], nil
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(spec, "%"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: no such job", spec)
+	}
+	for _, j := range m.jobs {
+		if j.Job == n {
+			return j, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: no such job", spec)
+}
+
+func (m *model) removeJob(run *commandRun) {
+	kept := m.jobs[:0]
+	for _, j := range m.jobs {
+		if j != run {
+			kept = append(kept, j)
+		}
+	}
+	m.jobs = kept
+	if m.waiting != nil {
+		delete(m.waiting, run.ID)
+		if len(m.waiting) == 0 {
+			m.waiting = nil
+		}
+	}
+}
+
+// jobFinished сообщает о завершении фонового задания.
+func (m *model) jobFinished(run *commandRun, msg commandExitMsg) {
+	state := "Done"
+	switch {
+	case errors.Is(msg.Error, errCancelled):
+		state = "Killed"
+	case msg.Error != nil:
+		state = msg.Error.Error()
+	case msg.Code != 0:
+		state = fmt.Sprintf("Exit %d", msg.Code)
+	}
+	line := fmt.Sprintf("[%d]  %-8s %s  (%s)", run.Job, state, run.Command, msg.Duration.Round(time.Second))
+	if state == "Done" {
+		line = jobStyle.Render(line)
+	} else {
+		line = stderrStyle.Render(line)
+	}
+	m.termOutput.add(line)
+	m.removeJob(run)
+	// Задания обычно меняют файлы: показываем результат в панелях
+	m.refreshPanelsAfterChange(m.leftDir)
+	m.refreshPanelsAfterChange(m.rightDir)
+	m.adjustScroll()
+}
+
+// jobBuiltin выполняет jobs, fg, kill или wait.
+func (m *model) jobBuiltin(args []string) tea.Cmd {
+	out := func(lines ...string) { m.termOutput.add(lines...) }
+
+	switch args[0] {
+	case "jobs":
+		if len(m.jobs) == 0 {
+			out("No background jobs.")
+		}
+		for _, j := range m.jobs {
+			out(fmt.Sprintf("[%d]  Running  %s  (%s)", j.Job, j.Command, time.Since(j.Started).Round(time.Second)))
+		}
+
+	case "fg":
+		if m.running != nil {
+			out("fg: a command is already running in the foreground")
+			break
+		}
+		spec := ""
+		if len(args) > 1 {
+			spec = args[1]
+		}
+		run, err := m.findJob(spec)
+		if err != nil {
+			out("fg: " + err.Error())
+			break
+		}
+		m.removeJob(run)
+		m.running = run
+		out(run.Command)
+
+	case "kill":
+		if len(args) == 1 {
+			out("usage: kill %job ...")
+			break
+		}
+		for _, spec := range args[1:] {
+			if !strings.HasPrefix(spec, "%") {
+				// Убивать можно только свои задания, иначе это обход политики
+				out("kill: " + spec + ": use %N to refer to a job")
+				continue
+			}
+			run, err := m.findJob(spec)
+			if err != nil {
+				out("kill: " + err.Error())
+				continue
+			}
+			run.cancel()
+		}
+
+	case "wait":
+		waiting := make(map[int]bool)
+		if len(args) == 1 {
+			for _, j := range m.jobs {
+				waiting[j.ID] = true
+			}
+		}
+		for _, spec := range args[1:] {
+			run, err := m.findJob(spec)
+			if err != nil {
+				out("wait: " + err.Error())
+				continue
+			}
+			waiting[run.ID] = true
+		}
+		if len(waiting) > 0 {
+			m.waiting = waiting
+			out(fmt.Sprintf("Waiting for %d job(s) (ctrl+c to stop waiting)...", len(waiting)))
+		}
+	}
+	return nil
+}
+
+// jobsIndicator — отметка о фоновых заданиях в строке ввода терминала.
+func (m model) jobsIndicator() string {
+	switch {
+	case m.waiting != nil:
+		return jobStyle.Render(fmt.Sprintf("[wait %d] ", len(m.waiting)))
+	case len(m.jobs) == 1:
+		return jobStyle.Render("[1 job] ")
+	case len(m.jobs) > 1:
+		return jobStyle.Render(fmt.Sprintf("[%d jobs] ", len(m.jobs)))
+	}
+	return ""
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type linkKind int
+
+const (
+	linkAbsolute linkKind = iota
+	linkRelative
+	linkHard
+	linkKindCount
+)
+
+func (k linkKind) String() string {
+	switch k {
+	case linkRelative:
+		return "symlink, relative"
+	case linkHard:
+		return "hard link"
+	}
+	return "symlink, absolute"
+}
+
+// linkDialog — создание ссылок на элементы активной панели в каталоге
+// другой панели. Для одного элемента вводится имя ссылки, для нескольких —
+// шаблон имён как в групповом переименовании.
+type linkDialog struct {
+	kind   linkKind
+	srcDir string
+	dstDir string
+	names  []string
+	input  textinput.Model
+	err    string
+}
+
+func (m *model) openLinkDialog() tea.Cmd {
+	if m.refuseInArchive("Link") {
+		return nil
+	}
+	names := m.activeSelection()
+	if len(names) == 0 {
+		items, cursor := m.activeItems(), m.leftCursor
+		if m.activePanel == 1 {
+			cursor = m.rightCursor
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		names = []string{items[cursor]}
+	}
+	d := &linkDialog{srcDir: m.activeDir(), dstDir: m.rightDir, names: names}
+	if m.activePanel == 1 {
+		d.dstDir = m.leftDir
+	}
+	if isArchiveDir(d.dstDir) {
+		m.termOutput.add("Link: the other panel is inside a read-only archive.")
+		return nil
+	}
+	d.input = textinput.New()
+	d.input.CharLimit = 255
+	d.input.Width = 50
+	if len(names) == 1 {
+		d.input.SetValue(filepath.Base(names[0]))
+	} else {
+		d.input.SetValue("{name}{ext}")
+	}
+	m.links = d
+	return d.input.Focus()
+}
+
+// targets — имена создаваемых ссылок по порядку names.
+func (d *linkDialog) targets() ([]string, error) {
+	if len(d.names) == 1 {
+		return []string{d.input.Value()}, nil
+	}
+	out := make([]string, len(d.names))
+	for i, name := range d.names {
+		t, err := expandTemplate(d.input.Value(), renameEntry{name: filepath.Base(name)}, i+1)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = t
+	}
+	return out, nil
+}
+
+// check проверяет имена и возможность создать ссылки до изменения ФС.
+func (d *linkDialog) check(targets []string) error {
+	seen := make(map[string]string)
+	for i, t := range targets {
+		if err := validName(t); err != nil {
+			return err
+		}
+		if other, ok := seen[t]; ok {
+			return fmt.Errorf("%s and %s get the same link name %s", other, d.names[i], t)
+		}
+		seen[t] = d.names[i]
+		if _, err := os.Lstat(filepath.Join(d.dstDir, t)); err == nil {
+			return fmt.Errorf("%s already exists in %s", t, d.dstDir)
+		}
+		info, err := os.Lstat(filepath.Join(d.srcDir, d.names[i]))
+		if err != nil {
+			return fmt.Errorf("%s no longer exists", d.names[i])
+		}
+		if d.kind == linkHard && info.IsDir() {
+			return fmt.Errorf("can't hard link directory %s", d.names[i])
+		}
+	}
+	return nil
+}
+
+func (d *linkDialog) create(name, target string) error {
+	src := filepath.Join(d.srcDir, name)
+	dst := filepath.Join(d.dstDir, target)
+	switch d.kind {
+	case linkRelative:
+		rel, err := filepath.Rel(d.dstDir, src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(rel, dst)
+	case linkHard:
+		return os.Link(src, dst)
+	}
+	return os.Symlink(src, dst)
+}
+
+func (m model) updateLinkDialog(msg tea.KeyMsg) (model, tea.Cmd) {
+	d := m.links
+	switch msg.String() {
+	case "esc":
+		m.links = nil
+		return m, nil
+	case "tab":
+		d.kind = (d.kind + 1) % linkKindCount
+		d.err = ""
+		return m, nil
+	case "shift+tab":
+		d.kind = (d.kind + linkKindCount - 1) % linkKindCount
+		d.err = ""
+		return m, nil
+	case "enter":
+		targets, err := d.targets()
+		if err == nil {
+			err = d.check(targets)
+		}
+		if err != nil {
+			d.err = err.Error()
+			return m, nil
+		}
+		created := 0
+		for i, name := range d.names {
+			if err := d.create(name, targets[i]); err != nil {
+				m.termOutput.add("Link: " + err.Error())
+				continue
+			}
+			created++
+		}
+		m.termOutput.add(fmt.Sprintf("Created %d link(s) (%s) in %s", created, d.kind, d.dstDir))
+		m.links = nil
+		if m.activePanel == 0 {
+			m.selectedLeft = make(map[string]bool)
+		} else {
+			m.selectedRight = make(map[string]bool)
+		}
+		m.refreshPanelsAfterChange(d.dstDir)
+		return m, nil
+	}
+	d.err = ""
+	var cmd tea.Cmd
+	d.input, cmd = d.input.Update(msg)
+	return m, cmd
+}
+
+func (m model) renderLinkDialog() string {
+	d := m.links
+	popupWidth := 64
+	popupStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("171")).
+		Padding(1, 2).
+		Width(popupWidth)
+	hint := lipgloss.NewStyle().Faint(true)
+
+	what := d.names[0]
+	if len(d.names) > 1 {
+		what = fmt.Sprintf("%d entries", len(d.names))
+	}
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Render("Link " + what + " into " + d.dstDir),
+		"",
+		"Type: ‹ " + d.kind.String() + " ›",
+		d.input.View(),
+	}
+	if len(d.names) > 1 {
+		lines = append(lines, hint.Render("Tokens: {name} {ext} {n} {n:03}"))
+		if targets, err := d.targets(); err == nil {
+			const shown = 5
+			for i, t := range targets[:min(shown, len(targets))] {
+				lines = append(lines, "  "+d.names[i]+" → "+t)
+			}
+			if len(targets) > shown {
+				lines = append(lines, hint.Render(fmt.Sprintf("  … and %d more", len(targets)-shown)))
+			}
+		}
+	}
+	if d.err != "" {
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(d.err))
+	}
+	lines = append(lines, "", hint.Render("Tab link type • Enter create • Esc cancel"))
+
+	popup := popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	x := (m.width - popupWidth) / 2
+	y := (m.height - lipgloss.Height(popup)) / 2
+	if y < 0 {
+		y = 0
+	}
+	return lipgloss.NewStyle().MarginLeft(x).MarginTop(y).Render(popup)
+}
+
+// linkInfo возвращает цель символической ссылки; broken — цель не существует.
+// Для обычных элементов ok == false.
+func linkInfo(path string) (target string, broken, ok bool) {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return "", false, false
+	}
+	target, err = os.Readlink(path)
+	if err != nil {
+		return "?", true, true
+	}
+	_, err = os.Stat(path)
+	return target, err != nil, true
+}
+
+// panelLink — цель символической ссылки, показанная в панели.
+type panelLink struct {
+	target string
+	broken bool
+}
+
+// panelLinks собирает цели ссылок среди элементов каталога dir.
+func panelLinks(dir string, items []string) map[string]panelLink {
+	links := make(map[string]panelLink)
+	for _, item := range items {
+		if target, broken, ok := linkInfo(filepath.Join(dir, item)); ok {
+			links[item] = panelLink{target: target, broken: broken}
+		}
+	}
+	return links
+}
+
+// followLink переводит активную панель в каталог цели ссылки под курсором
+// и ставит курсор на саму цель.
+func (m *model) followLink() {
+	items, cursor := m.activeItems(), m.leftCursor
+	if m.activePanel == 1 {
+		cursor = m.rightCursor
+	}
+	if len(items) == 0 {
+		return
+	}
+	path := filepath.Join(m.activeDir(), items[cursor])
+	target, broken, ok := linkInfo(path)
+	if !ok {
+		m.termOutput.add(items[cursor] + " is not a symbolic link")
+		return
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	target = filepath.Clean(target)
+	if broken {
+		m.termOutput.add("Broken link: " + items[cursor] + " → " + target)
+		return
+	}
+	m.setActiveDir(filepath.Dir(target))
+	name := filepath.Base(target)
+	for i, item := range m.activeItems() {
+		if item == name {
+			m.setCursor(i)
+			return
+		}
+	}
+	if strings.HasPrefix(name, ".") {
+		m.termOutput.add(name + " is hidden; press . to show hidden files")
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// macroContext — значения для подстановки в команды терминала.
+type macroContext struct {
+	file     string   // %f — элемент под курсором активной панели
+	other    string   // %F — тот же путь в другой панели
+	selected []string // %s — выделенные элементы (или элемент под курсором)
+	dir      string   // %d — каталог активной панели
+	otherDir string   // %D — каталог другой панели
+}
+
+var safeShellWord = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// quoteArg экранирует аргумент, только если в нём есть спецсимволы.
+func quoteArg(s string) string {
+	if s != "" && safeShellWord.MatchString(s) {
+		return s
+	}
+	return shellQuote(s)
+}
+
+// macroContext собирает значения макросов из состояния панелей.
+func (m model) macroContext() macroContext {
+	dir, otherDir := m.leftDir, m.rightDir
+	items, cursor, selected := m.leftItems, m.leftCursor, m.selectedLeft
+	if m.activePanel == 1 {
+		dir, otherDir = m.rightDir, m.leftDir
+		items, cursor, selected = m.rightItems, m.rightCursor, m.selectedRight
+	}
+
+	ctx := macroContext{dir: dir, otherDir: otherDir}
+	if len(items) > 0 {
+		name := items[cursor]
+		ctx.file = filepath.Join(dir, name)
+		ctx.other = filepath.Join(otherDir, name)
+	}
+	for name := range selected {
+		ctx.selected = append(ctx.selected, filepath.Join(dir, name))
+	}
+	sort.Strings(ctx.selected)
+	if len(ctx.selected) == 0 && ctx.file != "" {
+		ctx.selected = []string{ctx.file}
+	}
+	return ctx
+}
+
+// expandMacros подставляет %f, %F, %s, %d, %D и %% в строку команды.
+// Пути экранируются для шелла; неизвестные %-последовательности остаются как есть.
+func expandMacros(input string, ctx macroContext) string {
+	var b strings.Builder
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		if c != '%' || i == len(input)-1 {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch input[i] {
+		case 'f':
+			b.WriteString(quoteArg(ctx.file))
+		case 'F':
+			b.WriteString(quoteArg(ctx.other))
+		case 's':
+			quoted := make([]string, len(ctx.selected))
+			for j, p := range ctx.selected {
+				quoted[j] = quoteArg(p)
+			}
+			b.WriteString(strings.Join(quoted, " "))
+		case 'd':
+			b.WriteString(quoteArg(ctx.dir))
+		case 'D':
+			b.WriteString(quoteArg(ctx.otherDir))
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(input[i])
+		}
+	}
+	return b.String()
+}
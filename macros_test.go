@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestExpandMacros(t *testing.T) {
+	ctx := macroContext{
+		file:     "/home/u/a.txt",
+		other:    "/mnt/a.txt",
+		selected: []string{"/home/u/a.txt", "/home/u/my file"},
+		dir:      "/home/u",
+		otherDir: "/mnt/it's",
+	}
+	tests := []struct{ input, want string }{
+		{"cat %f", "cat /home/u/a.txt"},
+		{"diff %f %F", "diff /home/u/a.txt /mnt/a.txt"},
+		{"rm %s", "rm /home/u/a.txt '/home/u/my file'"},
+		{"cd %D", `cd '/mnt/it'\''s'`},
+		{"ls %d/x", "ls /home/u/x"},
+		{"echo 100%%", "echo 100%"},
+		{"echo %x %", "echo %x %"},
+		{"no macros", "no macros"},
+	}
+	for _, tt := range tests {
+		if got := expandMacros(tt.input, ctx); got != tt.want {
+			t.Errorf("expandMacros(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	if got := expandMacros("cat %f", macroContext{}); got != "cat ''" {
+		t.Errorf("empty %%f expanded to %q", got)
+	}
+}
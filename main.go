@@ -1,15 +1,17 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -31,6 +33,7 @@ type model struct {
 
 	leftDir, rightDir       string
 	leftItems, rightItems   []string
+	leftLinks, rightLinks   map[string]panelLink // цели ссылок среди элементов
 	activePanel             int
 	leftCursor, rightCursor int
 	leftScroll, rightScroll int
@@ -40,7 +43,7 @@ type model struct {
 	targetTermHeight int
 	termAnimating    bool
 
-	termOutput      []string
+	termOutput      *scrollback
 	termInput       textinput.Model
 	focusOnTerminal bool
 	showHiddenLeft  bool
@@ -56,6 +59,9 @@ type model struct {
 	renameTarget  string
 	renamePanel   int
 	renameOldPath string
+	renameErr     string // ошибка проверки, показывается в самом окне
+	// основа имени выделена: ввод заменяет её, расширение остаётся
+	renameStemSelected bool
 
 	copying     bool
 	copyPercent int
@@ -67,8 +73,71 @@ type model struct {
 	// selection maps per panel
 	selectedLeft  map[string]bool
 	selectedRight map[string]bool
+
+	// результат сравнения панелей (nil — сравнение не выполнялось)
+	compare *compareResult
+
+	// мастер синхронизации и выполняемый план
+	syncWizard *syncWizard
+	syncRun    *syncRun
+
+	mouse mouseState
+
+	// меню "Open with…"
+	openWith *openWithMenu
+
+	// навигация: счётчик в стиле vim (10j) и ожидание буквы после f
+	countPrefix int
+	pendingJump bool
+
+	// интерактивный шелл в pty вместо белого списка команд
+	shell     *shellSession
+	shellMode bool
+
+	// политика команд терминала и диалог подтверждения
+	policy  *policyStore
+	confirm *confirmDialog
+
+	// выполняемая сейчас внешняя команда (ctrl+c прерывает её)
+	running *commandRun
+
+	// фоновые задания (команды с &) и те, чьего завершения ждёт wait
+	jobs    []*commandRun
+	waiting map[int]bool
+
+	// стек каталогов pushd/popd
+	dirStack []string
+
+	// диалог группового переименования и последняя пачка для отмены
+	multiRename *multiRename
+	lastRename  *renameBatch
+
+	// окно создания файла, каталога или элемента из шаблона
+	create *createDialog
+
+	// окно создания ссылок в каталоге другой панели
+	links *linkDialog
+
+	// окно прав, владельца и времени изменения
+	props *propsDialog
+
+	// окно упаковки в архив
+	pack *packDialog
+
+	// выбор места распаковки архивов
+	extract *extractDialog
+
+	// поиск дубликатов: выбор деревьев и результаты, показанные в панелях
+	dupsDialog          *dupsDialog
+	leftDups, rightDups *dupResults
+
+	// история команд терминала и меню Tab-дополнения
+	history    *commandHistory
+	completion *completionMenu
 }
 
+var stderrStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("203"))
+
 type tickMsg time.Time
 
 type copyProgressMsg struct {
@@ -82,10 +151,17 @@ type copyDoneMsg struct {
 	Error    error
 }
 
-type runCommandMsg struct {
-	Command string
-	Output  string
-	Error   error
+type commandOutputMsg struct {
+	ID     int
+	Line   string
+	Stderr bool
+}
+
+type commandExitMsg struct {
+	ID       int
+	Code     int
+	Duration time.Duration
+	Error    error
 }
 
 func initialModel() model {
@@ -105,33 +181,48 @@ func initialModel() model {
 	leftItems := getDirItems(currentDir, showHiddenLeft)
 	rightItems := getDirItems(currentDir, showHiddenRight)
 
+	cfg, cfgErr := loadConfig()
+	termOutput := newScrollback(cfg.Scrollback)
+	termOutput.add(
+		"Welcome to demo terminal.",
+		"Type and press Enter to append lines.",
+	)
+	if cfgErr != nil {
+		termOutput.add("Config: " + cfgErr.Error())
+	}
+	policy := newPolicyStore()
+	if note := policy.reload(); note != "" {
+		termOutput.add(note)
+	}
+
 	return model{
 		leftDir:          currentDir,
 		rightDir:         currentDir,
 		leftItems:        leftItems,
 		rightItems:       rightItems,
+		leftLinks:        panelLinks(currentDir, leftItems),
+		rightLinks:       panelLinks(currentDir, rightItems),
 		showHiddenLeft:   showHiddenLeft,
 		showHiddenRight:  showHiddenRight,
 		terminalMode:     TermCompact,
 		terminalHeight:   6,
 		targetTermHeight: 6,
-		termOutput: []string{
-			"Welcome to demo terminal.",
-			"Type and press Enter to append lines.",
-		},
-		termInput:       ti,
-		clipboard:       []string{},
-		operation:       "",
-		renaming:        false,
-		renameInput:     textinput.New(),
-		selectedLeft:    make(map[string]bool),
-		selectedRight:   make(map[string]bool),
-		flashMessage:    "",
-		flashTimer:      time.Time{},
-		copying:         false,
-		copyPercent:     0,
-		copyFile:        "",
-		focusOnTerminal: false,
+		termOutput:       termOutput,
+		termInput:        ti,
+		clipboard:        []string{},
+		operation:        "",
+		renaming:         false,
+		renameInput:      textinput.New(),
+		selectedLeft:     make(map[string]bool),
+		selectedRight:    make(map[string]bool),
+		flashMessage:     "",
+		flashTimer:       time.Time{},
+		copying:          false,
+		copyPercent:      0,
+		copyFile:         "",
+		focusOnTerminal:  false,
+		policy:           policy,
+		history:          loadHistory(historyPath()),
 	}
 }
 
@@ -148,15 +239,29 @@ func getDirContents(dir string) ([]string, error) {
 }
 
 func main() {
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-	if err := p.Start(); err != nil {
+	p := tea.NewProgram(initialModel(), tea.WithAltScreen(), tea.WithMouseCellMotion())
+	final, err := p.Run()
+	if fm, ok := final.(model); ok {
+		fm.shutdown()
+	}
+	if err != nil {
 		fmt.Println("Error:", err)
 		os.Exit(1)
 	}
 }
 
+// shutdown сохраняет состояние сессии после выхода из программы.
+func (m model) shutdown() {
+	if m.history != nil {
+		if err := m.history.save(); err != nil {
+			fmt.Println("History:", err)
+		}
+	}
+	cleanupViewTemp()
+}
+
 func (m model) Init() tea.Cmd {
-	return textinput.Blink
+	return tea.Batch(textinput.Blink, watchPolicy())
 }
 
 func animateTerminalCmd() tea.Cmd {
@@ -164,56 +269,100 @@ func animateTerminalCmd() tea.Cmd {
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	nm := next.(model)
+	if nm.shell != nil {
+		// Шелл следует за активной панелью и размером терминальной области
+		nm.shell.syncDir(hostDir(nm.activeDir()))
+		nm.shell.resize(nm.shellSize())
+	}
+	return nm, cmd
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	var cmd tea.Cmd
 
-	// Если в режиме переименования — обрабатываем только ввод туда
-	if m.renaming {
-		switch msg := msg.(type) {
-		case tea.KeyMsg:
-			switch msg.String() {
-			case "enter":
-				newFilename := m.renameInput.Value()
-				newPath := filepath.Join(filepath.Dir(m.renameOldPath), newFilename)
-
-				err := os.Rename(m.renameOldPath, newPath)
-				if err != nil {
-					m.termOutput = append(m.termOutput, "Error renaming: "+err.Error())
-				} else {
-					m.termOutput = append(m.termOutput, "Renamed to: "+newFilename)
-					if m.renamePanel == 0 {
-						m.leftItems = getDirItems(m.leftDir, m.showHiddenLeft)
-						m.leftCursor, m.leftScroll = 0, 0
-					} else {
-						m.rightItems = getDirItems(m.rightDir, m.showHiddenRight)
-						m.rightCursor, m.rightScroll = 0, 0
-					}
-				}
-				m.renaming = false
-				m.renameInput.SetValue("")
-			case "esc":
-				m.renaming = false
-				m.renameInput.SetValue("")
-			default:
-				m.renameInput, cmd = m.renameInput.Update(msg)
-				cmds = append(cmds, cmd)
-			}
-		}
-		return m, tea.Batch(cmds...)
+	// Пока открыто окно переименования, клавиши идут только в него. Прочие
+	// сообщения обрабатываются как обычно: вывод команд, таймеры и шаги
+	// синхронизации перезапускают свои цепочки ожидания и терять их нельзя.
+	if km, ok := msg.(tea.KeyMsg); ok && m.renaming {
+		return m.updateRenamePopup(km)
+	}
+
+	if km, ok := msg.(tea.KeyMsg); ok && m.confirm != nil {
+		return m.updateConfirm(km)
+	}
+	if km, ok := msg.(tea.KeyMsg); ok && m.syncWizard != nil {
+		return m.updateSyncWizard(km)
+	}
+	if km, ok := msg.(tea.KeyMsg); ok && m.openWith != nil {
+		return m.updateOpenWith(km)
+	}
+	if km, ok := msg.(tea.KeyMsg); ok && m.multiRename != nil {
+		return m.updateMultiRename(km)
+	}
+	if km, ok := msg.(tea.KeyMsg); ok && m.create != nil {
+		return m.updateCreate(km)
+	}
+	if km, ok := msg.(tea.KeyMsg); ok && m.links != nil {
+		return m.updateLinkDialog(km)
+	}
+	if km, ok := msg.(tea.KeyMsg); ok && m.props != nil {
+		return m.updateProps(km)
+	}
+	if km, ok := msg.(tea.KeyMsg); ok && m.pack != nil {
+		return m.updatePack(km)
+	}
+	if km, ok := msg.(tea.KeyMsg); ok && m.extract != nil {
+		return m.updateExtract(km)
+	}
+	if km, ok := msg.(tea.KeyMsg); ok && m.dupsDialog != nil {
+		return m.updateDupsDialog(km)
 	}
 
 	switch msg := msg.(type) {
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
+
 	case tea.KeyMsg:
 		key := msg.String()
 
+		if key == "ctrl+o" && !(m.focusOnTerminal && m.shellMode) {
+			cmd := m.toggleShell()
+			return m, cmd
+		}
+
 		// Если фокус в терминале — в первую очередь обрабатываем это отдельно
+		if m.focusOnTerminal && m.shellMode && m.shell != nil {
+			return m.updateShellKey(msg)
+		}
 		if m.focusOnTerminal {
 			// Обрабатываем сочетания, которые должны работать даже когда терминал в фокусе
 			switch key {
-			case "ctrl+c", "q":
+			case "ctrl+c":
+				if m.running != nil {
+					m.running.cancel()
+					m.termOutput.add("^C")
+					return m, tea.Batch(cmds...)
+				}
+				if m.waiting != nil {
+					m.waiting = nil
+					m.termOutput.add("^C")
+					return m, tea.Batch(cmds...)
+				}
 				return m, tea.Quit
 
 			case "esc":
+				if m.termOutput.search != nil {
+					m.termOutput.updateSearchKey(msg)
+					return m, tea.Batch(cmds...)
+				}
+				if m.history.search != nil {
+					m.termInput.SetValue(m.history.search.draft)
+					m.history.search = nil
+					return m, tea.Batch(cmds...)
+				}
 				m.focusOnTerminal = false
 				m.termInput.Blur()
 				return m, tea.Batch(cmds...)
@@ -268,6 +417,58 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, tea.Batch(cmds...)
 			}
 
+			// Поиск по истории перехватывает ввод; Enter выполняет найденную команду
+			if m.history.search != nil && m.history.updateSearchKey(&m, msg) {
+				return m, tea.Batch(cmds...)
+			}
+			// Поиск по выводу перехватывает ввод целиком
+			if m.termOutput.search != nil {
+				m.termOutput.updateSearchKey(msg)
+				return m, tea.Batch(cmds...)
+			}
+
+			if key == "tab" {
+				m.complete()
+				return m, tea.Batch(cmds...)
+			}
+			m.completion = nil
+
+			switch key {
+			case "up":
+				if prev, ok := m.history.prev(m.termInput.Value(), m.activeDir()); ok {
+					m.termInput.SetValue(prev)
+					m.termInput.CursorEnd()
+				}
+				return m, tea.Batch(cmds...)
+			case "down":
+				if next, ok := m.history.next(m.activeDir()); ok {
+					m.termInput.SetValue(next)
+					m.termInput.CursorEnd()
+				}
+				return m, tea.Batch(cmds...)
+			case "ctrl+r":
+				m.history.search = &historySearch{draft: m.termInput.Value()}
+				return m, tea.Batch(cmds...)
+			case "ctrl+s":
+				m.termOutput.search = &scrollSearch{current: -1}
+				return m, tea.Batch(cmds...)
+			case "pgup":
+				m.termOutput.scroll(m.termOutputRows())
+				return m, tea.Batch(cmds...)
+			case "pgdown":
+				m.termOutput.scroll(-m.termOutputRows())
+				return m, tea.Batch(cmds...)
+			case "ctrl+f":
+				m.history.dirOnly = !m.history.dirOnly
+				m.history.reset()
+				if m.history.dirOnly {
+					m.termOutput.add("History: this directory only.")
+				} else {
+					m.termOutput.add("History: all directories.")
+				}
+				return m, tea.Batch(cmds...)
+			}
+
 			m.termInput.Width = m.width - 4
 			// Не глобальная комбинация — проксируем в textinput
 			m.termInput, cmd = m.termInput.Update(msg)
@@ -277,46 +478,36 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if key == "enter" {
 				input := strings.TrimSpace(m.termInput.Value())
 				if input != "" {
-					parts := strings.Fields(input)
-					if len(parts) > 0 && parts[0] == "cd" {
-						var newPath string
-						if len(parts) == 1 || parts[1] == "~" {
-							newPath = os.Getenv("HOME")
-						} else {
-							base := m.leftDir
-							if m.activePanel == 1 {
-								base = m.rightDir
-							}
-							newPath = parts[1]
-							if !filepath.IsAbs(newPath) {
-								newPath = filepath.Join(base, newPath)
-							}
-						}
-						if fi, err := os.Stat(newPath); err == nil && fi.IsDir() {
-							if m.activePanel == 0 {
-								m.leftDir = newPath
-								m.leftItems = getDirItems(m.leftDir, m.showHiddenLeft)
-								m.leftCursor, m.leftScroll = 0, 0
-							} else {
-								m.rightDir = newPath
-								m.rightItems = getDirItems(m.rightDir, m.showHiddenRight)
-								m.rightCursor, m.rightScroll = 0, 0
-							}
-							m.termOutput = append(m.termOutput, fmt.Sprintf("$ %s\n--> cd %s", input, newPath))
-						} else {
-							m.termOutput = append(m.termOutput, fmt.Sprintf("$ %s\ncd: no such directory: %s", input, newPath))
-						}
+					// Новая команда возвращает вид к концу вывода
+					m.termOutput.offset = 0
+					if err := m.history.add(input, m.activeDir()); err != nil {
+						m.termOutput.add("History: " + err.Error())
+					}
+					// Макросы раскрываются до разбора, в историю попадает исходный текст
+					command := expandMacros(input, m.macroContext())
+					p, err := parseCommandLine(command, m.activeDir(), os.Getenv)
+					if err != nil {
+						m.termOutput.add("$ "+input, err.Error())
 						m.termInput.SetValue("")
 						return m, tea.Batch(cmds...)
 					}
+					if m.waiting != nil {
+						m.termOutput.add("$ "+input, "wait: still waiting for jobs (ctrl+c to stop waiting)")
+						m.termInput.SetValue("")
+						return m, tea.Batch(cmds...)
+					}
+					if handled, cmd := m.runBuiltin(p, input); handled {
+						m.termInput.SetValue("")
+						return m, tea.Batch(append(cmds, cmd)...)
+					}
 
 					// Внешняя команда
-					m.termOutput = append(m.termOutput, "$ "+input)
-					workingDir := m.leftDir
-					if m.activePanel == 1 {
-						workingDir = m.rightDir
+					m.termOutput.add("$ " + input)
+					if command != input {
+						m.termOutput.add("→ " + command)
 					}
-					cmds = append(cmds, runCommandAsync(input, workingDir))
+					workingDir := hostDir(m.activeDir())
+					cmds = append(cmds, m.checkCommand(p, workingDir))
 					m.termInput.SetValue("")
 					return m, tea.Batch(cmds...)
 				}
@@ -330,30 +521,33 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		// Ниже — обработка клавиш когда фокуса на терминале нет
+		if m.pendingJump {
+			m.pendingJump = false
+			if len(msg.Runes) == 1 {
+				m.jumpToLetter(msg.Runes[0])
+			}
+			m.countPrefix = 0
+			return m, tea.Batch(cmds...)
+		}
+		if len(key) == 1 && key[0] >= '0' && key[0] <= '9' && (key != "0" || m.countPrefix > 0) {
+			m.countPrefix = m.countPrefix*10 + int(key[0]-'0')
+			if m.countPrefix > 99999 {
+				m.countPrefix = 99999
+			}
+			return m, tea.Batch(cmds...)
+		}
+
 		switch key {
 		case "ctrl+c", "q":
+			if key == "ctrl+c" && m.running != nil {
+				m.running.cancel()
+				m.termOutput.add("^C")
+				break
+			}
 			return m, tea.Quit
 
 		case " ":
-			if m.activePanel == 0 {
-				if len(m.leftItems) > 0 {
-					selected := m.leftItems[m.leftCursor]
-					if m.selectedLeft[selected] {
-						delete(m.selectedLeft, selected)
-					} else {
-						m.selectedLeft[selected] = true
-					}
-				}
-			} else {
-				if len(m.rightItems) > 0 {
-					selected := m.rightItems[m.rightCursor]
-					if m.selectedRight[selected] {
-						delete(m.selectedRight, selected)
-					} else {
-						m.selectedRight[selected] = true
-					}
-				}
-			}
+			m.toggleSelection()
 
 		case "p":
 			if len(m.clipboard) > 0 {
@@ -363,6 +557,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				} else {
 					destDir = m.rightDir
 				}
+				if isArchiveDir(destDir) {
+					m.termOutput.add("Archives are read-only: paste into a regular directory.")
+					break
+				}
 
 				for _, sourceFile := range m.clipboard {
 					destPath := filepath.Join(destDir, filepath.Base(sourceFile))
@@ -372,21 +570,25 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						ctx := context.Background()
 						c := copyFileAsync(ctx, sourceFile, destPath)
 						cmds = append(cmds, c)
-						m.termOutput = append(m.termOutput, fmt.Sprintf("Started copying %s → %s", filepath.Base(sourceFile), destDir))
+						m.termOutput.add(fmt.Sprintf("Started copying %s → %s", filepath.Base(sourceFile), destDir))
 					case "move":
+						if isArchiveMember(sourceFile) {
+							m.termOutput.add("Can't move out of a read-only archive, copy instead: " + sourceFile)
+							continue
+						}
 						err := os.Rename(sourceFile, destPath)
 						if err != nil {
-							m.termOutput = append(m.termOutput, "Error moving: "+err.Error())
+							m.termOutput.add("Error moving: " + err.Error())
 						} else {
-							m.termOutput = append(m.termOutput, "Moved to: "+destPath)
+							m.termOutput.add("Moved to: " + destPath)
 							m.refreshPanelsAfterChange(filepath.Dir(sourceFile))
 							m.refreshPanelsAfterChange(destDir)
 						}
 					}
 				}
 
-				m.leftItems = getDirItems(m.leftDir, m.showHiddenLeft)
-				m.rightItems = getDirItems(m.rightDir, m.showHiddenRight)
+				m.reloadPanel(0)
+				m.reloadPanel(1)
 				m.clipboard = []string{}
 				m.operation = ""
 				m.selectedLeft = make(map[string]bool)
@@ -416,7 +618,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 			m.operation = "copy"
-			m.termOutput = append(m.termOutput, "Copied to clipboard.")
+			m.termOutput.add("Copied to clipboard.")
 			m.selectedLeft = make(map[string]bool)
 			m.selectedRight = make(map[string]bool)
 
@@ -442,7 +644,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 			m.operation = "move"
-			m.termOutput = append(m.termOutput, "Ready to move.")
+			m.termOutput.add("Ready to move.")
 			m.selectedLeft = make(map[string]bool)
 			m.selectedRight = make(map[string]bool)
 
@@ -467,18 +669,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			if len(targets) == 0 {
-				m.termOutput = append(m.termOutput, "Nothing to delete.")
+				m.termOutput.add("Nothing to delete.")
 			} else {
 				for _, t := range targets {
 					err := os.RemoveAll(t)
 					if err != nil {
-						m.termOutput = append(m.termOutput, "Error deleting "+t+": "+err.Error())
+						m.termOutput.add("Error deleting " + t + ": " + err.Error())
 					} else {
-						m.termOutput = append(m.termOutput, "Deleted: "+filepath.Base(t))
+						m.termOutput.add("Deleted: " + filepath.Base(t))
 					}
 				}
-				m.leftItems = getDirItems(m.leftDir, m.showHiddenLeft)
-				m.rightItems = getDirItems(m.rightDir, m.showHiddenRight)
+				m.reloadPanel(0)
+				m.reloadPanel(1)
 				m.leftCursor, m.leftScroll = 0, 0
 				m.rightCursor, m.rightScroll = 0, 0
 				m.selectedLeft = make(map[string]bool)
@@ -486,38 +688,93 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "r":
-			m.renaming = true
-			if m.activePanel == 0 {
-				selected := m.leftItems[m.leftCursor]
-				m.renameOldPath = filepath.Join(m.leftDir, selected)
-				m.renamePanel = 0
-			} else {
-				selected := m.rightItems[m.rightCursor]
-				m.renameOldPath = filepath.Join(m.rightDir, selected)
-				m.renamePanel = 1
+			cmds = append(cmds, m.openRenamePopup())
+
+		case "R":
+			cmds = append(cmds, m.startBulkRename())
+
+		case "M":
+			m.openMultiRename()
+
+		case "u":
+			m.undoRename()
+
+		case "n":
+			cmds = append(cmds, m.openCreate(createFile))
+		case "N":
+			cmds = append(cmds, m.openCreate(createDir))
+		case "T":
+			cmds = append(cmds, m.openCreate(createTemplate))
+
+		case "L":
+			cmds = append(cmds, m.openLinkDialog())
+		case "F":
+			m.followLink()
+
+		case "P":
+			cmds = append(cmds, m.openProps())
+
+		case "Z":
+			cmds = append(cmds, m.openPack())
+		case "X":
+			m.openExtract()
+
+		case "=", "#":
+			m.termOutput.add("Comparing panels...")
+			cmds = append(cmds, compareDirsAsync(m.leftDir, m.rightDir, m.leftItems, m.rightItems, key == "#"))
+
+		case "*":
+			n := m.selectByCompare(func(st compareStatus) bool {
+				return st != cmpNone && st != cmpIdentical
+			})
+			m.termOutput.add(fmt.Sprintf("Selected %d differing entries in %s panel.", n, panelLabel(m.activePanel)))
+
+		case "+":
+			n := m.selectByCompare(func(st compareStatus) bool {
+				return st == cmpOnlyHere || st == cmpNewer
+			})
+			m.termOutput.add(fmt.Sprintf("Selected %d new/newer entries in %s panel.", n, panelLabel(m.activePanel)))
+
+		case "esc":
+			m.compare = nil
+			m.closeDups()
+
+		case "U":
+			m.openDups()
+
+		case "<", ">":
+			m.selectDuplicates(key == ">")
+
+		case "O":
+			dir, items, cursor := m.leftDir, m.leftItems, m.leftCursor
+			if m.activePanel == 1 {
+				dir, items, cursor = m.rightDir, m.rightItems, m.rightCursor
 			}
-			m.renameInput = textinput.New()
-			m.renameInput.Placeholder = filepath.Base(m.renameOldPath)
-			m.renameInput.Focus()
-			m.renameInput.CharLimit = 256
-			m.renameInput.Width = 30
-			cmd = m.renameInput.Focus()
-			cmds = append(cmds, cmd)
+			if len(items) > 0 {
+				cmds = append(cmds, openWithCmd(filepath.Join(dir, items[cursor])))
+			}
+
+		case "S":
+			if m.syncRun != nil {
+				m.termOutput.add("Sync is already running.")
+				break
+			}
+			m.syncWizard = newSyncWizard()
 
 		case "x":
 			m.clipboard = []string{}
 			m.operation = ""
-			m.termOutput = append(m.termOutput, "Clipboard cleared.")
+			m.termOutput.add("Clipboard cleared.")
 			m.selectedLeft = make(map[string]bool)
 			m.selectedRight = make(map[string]bool)
 
 		case ".":
 			if m.activePanel == 0 {
 				m.showHiddenLeft = !m.showHiddenLeft
-				m.leftItems = getDirItems(m.leftDir, m.showHiddenLeft)
+				m.reloadPanel(0)
 			} else {
 				m.showHiddenRight = !m.showHiddenRight
-				m.rightItems = getDirItems(m.rightDir, m.showHiddenRight)
+				m.reloadPanel(1)
 			}
 
 		case "alt+left":
@@ -538,14 +795,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				newPath := filepath.Dir(m.leftDir)
 				if newPath != m.leftDir {
 					m.leftDir = newPath
-					m.leftItems = getDirItems(m.leftDir, m.showHiddenLeft)
+					m.reloadPanel(0)
 					m.leftCursor, m.leftScroll = 0, 0
 				}
 			} else {
 				newPath := filepath.Dir(m.rightDir)
 				if newPath != m.rightDir {
 					m.rightDir = newPath
-					m.rightItems = getDirItems(m.rightDir, m.showHiddenRight)
+					m.reloadPanel(1)
 					m.rightCursor, m.rightScroll = 0, 0
 				}
 			}
@@ -557,13 +814,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				selected := m.leftItems[m.leftCursor]
 				newPath := filepath.Join(m.leftDir, selected)
-				fileInfo, err := os.Stat(newPath)
-				if err == nil && fileInfo.IsDir() {
+				if canEnter(newPath) {
 					m.leftDir = newPath
-					m.leftItems = getDirItems(m.leftDir, m.showHiddenLeft)
+					m.reloadPanel(0)
 					m.leftCursor, m.leftScroll = 0, 0
 				} else {
-					if len(m.clipboard) > 0 {
+					if len(m.clipboard) > 0 && isArchiveDir(m.leftDir) {
+						m.termOutput.add("Archives are read-only: paste into a regular directory.")
+					} else if len(m.clipboard) > 0 {
 						destDir := m.leftDir
 						for _, sourceFile := range m.clipboard {
 							destPath := filepath.Join(destDir, filepath.Base(sourceFile))
@@ -571,17 +829,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							case "copy":
 								err := copyFile(sourceFile, destPath)
 								if err != nil {
-									m.termOutput = append(m.termOutput, "Error copying: "+err.Error())
+									m.termOutput.add("Error copying: " + err.Error())
 								} else {
-									m.termOutput = append(m.termOutput, "Copied to: "+destPath)
+									m.termOutput.add("Copied to: " + destPath)
 									m.refreshPanelsAfterChange(destDir)
 								}
 							case "move":
 								err := os.Rename(sourceFile, destPath)
 								if err != nil {
-									m.termOutput = append(m.termOutput, "Error moving: "+err.Error())
+									m.termOutput.add("Error moving: " + err.Error())
 								} else {
-									m.termOutput = append(m.termOutput, "Moved to: "+destPath)
+									m.termOutput.add("Moved to: " + destPath)
 									m.refreshPanelsAfterChange(filepath.Dir(sourceFile))
 									m.refreshPanelsAfterChange(destDir)
 								}
@@ -592,7 +850,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.selectedLeft = make(map[string]bool)
 						m.selectedRight = make(map[string]bool)
 					} else {
-						m.termOutput = append(m.termOutput, "Run: "+newPath)
+						cmds = append(cmds, openFileCmd(newPath))
 					}
 				}
 			} else {
@@ -601,13 +859,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				selected := m.rightItems[m.rightCursor]
 				newPath := filepath.Join(m.rightDir, selected)
-				fileInfo, err := os.Stat(newPath)
-				if err == nil && fileInfo.IsDir() {
+				if canEnter(newPath) {
 					m.rightDir = newPath
-					m.rightItems = getDirItems(m.rightDir, m.showHiddenRight)
+					m.reloadPanel(1)
 					m.rightCursor, m.rightScroll = 0, 0
 				} else {
-					if len(m.clipboard) > 0 {
+					if len(m.clipboard) > 0 && isArchiveDir(m.rightDir) {
+						m.termOutput.add("Archives are read-only: paste into a regular directory.")
+					} else if len(m.clipboard) > 0 {
 						destDir := m.rightDir
 						for _, sourceFile := range m.clipboard {
 							destPath := filepath.Join(destDir, filepath.Base(sourceFile))
@@ -615,17 +874,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							case "copy":
 								err := copyFile(sourceFile, destPath)
 								if err != nil {
-									m.termOutput = append(m.termOutput, "Error copying: "+err.Error())
+									m.termOutput.add("Error copying: " + err.Error())
 								} else {
-									m.termOutput = append(m.termOutput, "Copied to: "+destPath)
+									m.termOutput.add("Copied to: " + destPath)
 									m.refreshPanelsAfterChange(destDir)
 								}
 							case "move":
 								err := os.Rename(sourceFile, destPath)
 								if err != nil {
-									m.termOutput = append(m.termOutput, "Error moving: "+err.Error())
+									m.termOutput.add("Error moving: " + err.Error())
 								} else {
-									m.termOutput = append(m.termOutput, "Moved to: "+destPath)
+									m.termOutput.add("Moved to: " + destPath)
 									m.refreshPanelsAfterChange(filepath.Dir(sourceFile))
 									m.refreshPanelsAfterChange(destDir)
 								}
@@ -636,41 +895,41 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.selectedLeft = make(map[string]bool)
 						m.selectedRight = make(map[string]bool)
 					} else {
-						m.termOutput = append(m.termOutput, "Run: "+newPath)
+						cmds = append(cmds, openFileCmd(newPath))
 					}
 				}
 			}
 
-		case "up":
-			if m.activePanel == 0 {
-				if m.leftCursor > 0 {
-					m.leftCursor--
-				}
-				if m.leftCursor < m.leftScroll {
-					m.leftScroll = m.leftCursor
-				}
-			} else {
-				if m.rightCursor > 0 {
-					m.rightCursor--
-				}
-				if m.rightCursor < m.rightScroll {
-					m.rightScroll = m.rightCursor
-				}
-			}
+		case "up", "k":
+			m.moveCursor(-m.takeCount())
 
-		case "down":
-			if m.activePanel == 0 {
-				if m.leftCursor < len(m.leftItems)-1 {
-					m.leftCursor++
-				}
-				m.adjustScroll()
+		case "down", "j":
+			m.moveCursor(m.takeCount())
+
+		case "pgup":
+			m.moveCursor(-m.visibleRows() * m.takeCount())
+
+		case "pgdown":
+			m.moveCursor(m.visibleRows() * m.takeCount())
+
+		case "home":
+			m.setCursor(0)
+
+		case "end":
+			m.moveCursor(len(m.leftItems) + len(m.rightItems))
+
+		case "G":
+			// как в vim: 10G — на десятый элемент, G без счётчика — в конец
+			if m.countPrefix > 0 {
+				m.setCursor(0)
+				m.moveCursor(m.takeCount() - 1)
 			} else {
-				if m.rightCursor < len(m.rightItems)-1 {
-					m.rightCursor++
-				}
-				m.adjustScroll()
+				m.moveCursor(len(m.leftItems) + len(m.rightItems))
 			}
 
+		case "f":
+			m.pendingJump = true
+
 		case "ctrl+up":
 			m.targetTermHeight++
 			if m.targetTermHeight > m.height-3 {
@@ -701,6 +960,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.termAnimating = true
 			cmds = append(cmds, animateTerminalCmd())
 		}
+		// счётчик действует только на следующую команду перемещения
+		m.countPrefix = 0
 	// конец case tea.KeyMsg
 
 	case copyProgressMsg:
@@ -708,31 +969,139 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.copyFile = msg.Filename
 		m.copyPercent = msg.Percent
 		if msg.Percent%10 == 0 {
-			m.termOutput = append(m.termOutput, fmt.Sprintf("Copying %s... %d%%", msg.Filename, msg.Percent))
+			m.termOutput.add(fmt.Sprintf("Copying %s... %d%%", msg.Filename, msg.Percent))
 		}
 
 	case copyDoneMsg:
 		m.copying = false
 		if msg.Success {
-			m.termOutput = append(m.termOutput, fmt.Sprintf("Copied %s successfully!", msg.Filename))
+			m.termOutput.add(fmt.Sprintf("Copied %s successfully!", msg.Filename))
 			destDir := filepath.Dir(msg.Filename)
 			m.refreshPanelsAfterChange(destDir)
 			m.flashMessage = fmt.Sprintf("Copied: %s", msg.Filename)
 			m.flashTimer = time.Now()
 		} else {
-			m.termOutput = append(m.termOutput, fmt.Sprintf("Failed to copy %s: %v", msg.Filename, msg.Error))
+			m.termOutput.add(fmt.Sprintf("Failed to copy %s: %v", msg.Filename, msg.Error))
 			m.flashMessage = fmt.Sprintf("Error copying %s", msg.Filename)
 			m.flashTimer = time.Now()
 		}
 
-	case runCommandMsg:
+	case bulkEditMsg:
+		cmds = append(cmds, m.finishBulkEdit(msg))
+
+	case builtinOutputMsg:
+		m.termOutput.add(msg.Lines...)
+
+	case compareDoneMsg:
+		m.compare = msg.Result
+		m.termOutput.add(compareSummary(msg.Result))
+
+	case syncPlanMsg:
+		if m.syncWizard != nil {
+			m.syncWizard.planning = false
+			m.syncWizard.plan = msg.Plan
+			m.syncWizard.err = msg.Err
+			if msg.Err == nil && msg.Plan == nil {
+				m.syncWizard.plan = []syncAction{}
+			}
+		}
+
+	case syncStepMsg:
+		if m.syncRun != nil {
+			a := m.syncRun.plan[msg.Index]
+			if msg.Err != nil {
+				m.syncRun.failed++
+				m.termOutput.add(fmt.Sprintf("Sync error (%s): %v", a.Rel, msg.Err))
+			}
+			m.syncRun.index = msg.Index + 1
+			if c := m.nextSyncStep(); c != nil {
+				cmds = append(cmds, c)
+			} else {
+				m.finishSync()
+			}
+		}
+
+	case openHandlerMsg:
+		if msg.Err != nil {
+			m.termOutput.add(fmt.Sprintf("Open %s: %v", msg.Path, msg.Err))
+		} else {
+			cmds = append(cmds, launchEntry(msg.Entry, msg.Path))
+		}
+
+	case openWithMsg:
+		m.openWith = msg.Menu
+
+	case openDoneMsg:
+		if msg.Err != nil {
+			m.termOutput.add(fmt.Sprintf("Open %s: %v", msg.Path, msg.Err))
+		} else {
+			m.termOutput.add(fmt.Sprintf("Opened %s with %s", filepath.Base(msg.Path), msg.App))
+		}
+
+	case shellOutputMsg:
+		if m.shell != nil {
+			cmds = append(cmds, m.handleShellOutput(msg.Data))
+		}
+
+	case shellExitMsg:
+		if m.shell != nil {
+			m.shell.close()
+			m.shell = nil
+			m.shellMode = false
+			if m.focusOnTerminal {
+				m.termInput.Focus()
+			}
+			status := "Shell exited."
+			if msg.Err != nil {
+				status = "Shell exited: " + msg.Err.Error()
+			}
+			m.termOutput.add(status)
+		}
+
+	case policyCheckMsg:
+		if note := m.policy.reload(); note != "" {
+			m.termOutput.add(note)
+		}
+		cmds = append(cmds, watchPolicy())
+
+	case commandOutputMsg:
+		run := m.findRun(msg.ID)
+		if run == nil {
+			break
+		}
+		line := msg.Line
+		if msg.Stderr {
+			line = stderrStyle.Render(line)
+		}
+		if run != m.running {
+			line = jobStyle.Render(fmt.Sprintf("[%d] ", run.Job)) + line
+		}
+		m.termOutput.add(line)
+		cmds = append(cmds, run.wait())
+
+	case commandExitMsg:
+		run := m.findRun(msg.ID)
+		if run == nil {
+			break
+		}
+		if run.then != nil && msg.Error == nil && msg.Code == 0 {
+			run.then(&m)
+		}
+		if run != m.running {
+			m.jobFinished(run, msg)
+			break
+		}
+		status := fmt.Sprintf("[exit %d · %s]", msg.Code, msg.Duration.Round(time.Millisecond))
 		if msg.Error != nil {
-			m.termOutput = append(m.termOutput, fmt.Sprintf("Error: %v", msg.Error))
+			status = fmt.Sprintf("[%v · %s]", msg.Error, msg.Duration.Round(time.Millisecond))
 		}
-		if msg.Output != "" {
-			lines := strings.Split(strings.TrimRight(msg.Output, "\n"), "\n")
-			m.termOutput = append(m.termOutput, lines...)
+		if msg.Code != 0 || msg.Error != nil {
+			status = stderrStyle.Render(status)
+		} else {
+			status = lipgloss.NewStyle().Faint(true).Render(status)
 		}
+		m.termOutput.add(status)
+		m.running = nil
 
 	case tea.WindowSizeMsg:
 		m.width, m.height = msg.Width, msg.Height
@@ -743,6 +1112,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.terminalMode == TermExpanded {
 			m.targetTermHeight = m.height / 2
 		}
+		m.adjustScroll()
 
 	case tickMsg:
 		if m.termAnimating {
@@ -759,6 +1129,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				}
 				m.terminalHeight += step
+				m.adjustScroll()
 				cmds = append(cmds, animateTerminalCmd())
 			}
 		}
@@ -770,6 +1141,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // copyFile копирует файл или директорию (включая вложенные)
 func copyFile(src, dst string) error {
+	if archive, inner, ok := splitArchivePath(src); ok && inner != "" {
+		return extractArchive(context.Background(), archive, inner, dst, true, nil)
+	}
 	info, err := os.Lstat(src)
 	if err != nil {
 		return err
@@ -827,6 +1201,12 @@ func copyFile(src, dst string) error {
 // copyFileAsync выполняет копирование файла в фоне и шлёт сообщения о прогрессе.
 func copyFileAsync(ctx context.Context, src, dst string) tea.Cmd {
 	return func() tea.Msg {
+		if archive, inner, ok := splitArchivePath(src); ok && inner != "" {
+			if err := extractArchive(ctx, archive, inner, dst, true, nil); err != nil {
+				return copyDoneMsg{Filename: src, Success: false, Error: err}
+			}
+			return copyDoneMsg{Filename: dst, Success: true}
+		}
 		srcInfo, err := os.Lstat(src)
 		if err != nil {
 			return copyDoneMsg{Filename: src, Success: false, Error: err}
@@ -836,7 +1216,7 @@ func copyFileAsync(ctx context.Context, src, dst string) tea.Cmd {
 			if err := copyFile(src, dst); err != nil {
 				return copyDoneMsg{Filename: src, Success: false, Error: err}
 			}
-			return copyDoneMsg{Filename: src, Success: true}
+			return copyDoneMsg{Filename: dst, Success: true}
 		}
 
 		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
@@ -867,121 +1247,209 @@ func copyFileAsync(ctx context.Context, src, dst string) tea.Cmd {
 	}
 }
 
-// runCommandAsync выполняет команду оболочки в фоне и возвращает результат.
-func runCommandAsync(command string, workingDir string) tea.Cmd {
-	return func() tea.Msg {
-		cmdStr := strings.TrimSpace(command)
-		if cmdStr == "" {
-			return runCommandMsg{
-				Command: command,
-				Output:  "",
-				Error:   fmt.Errorf("empty command"),
-			}
-		}
-
-		parts := strings.Fields(cmdStr)
-		if len(parts) == 0 {
-			return runCommandMsg{
-				Command: command,
-				Output:  "",
-				Error:   fmt.Errorf("empty command"),
-			}
-		}
+// commandRun — запущенная внешняя команда, чей вывод приходит построчно.
+type commandRun struct {
+	ID      int
+	Job     int // номер фонового задания, 0 — запущена на переднем плане
+	Command string
+	Started time.Time
+	cancel  context.CancelFunc
+	events  chan tea.Msg
+	then    func(m *model) // внутреннее задание: вызывается после успешного завершения
+}
 
-		cmdName := parts[0]
-		args := parts[1:]
-
-		// НЕ запускаем shell (sh -c) — запускаем конкретную команду.
-		// whitelist — добавляйте сюда только безопасные команды, которые хотите разрешить.
-		allowed := map[string]bool{
-			"ls":   true,
-			"pwd":  true,
-			"cat":  true,
-			"echo": true,
-			"head": true,
-			"tail": true,
-			"stat": true,
-			"date": true,
-			"mpv":  true,
-		}
+var nextCommandID int
 
-		// Если команда — cd, сообщаем об этом как ошибке: cd обрабатывается в приложении как builtin.
-		if cmdName == "cd" {
-			return runCommandMsg{
-				Command: command,
-				Output:  "",
-				Error:   fmt.Errorf("cd is a builtin and handled by the application"),
-			}
-		}
+// errCancelled — команда прервана пользователем (ctrl+c или kill).
+var errCancelled = errors.New("cancelled")
 
-		if !allowed[cmdName] {
-			return runCommandMsg{
-				Command: command,
-				Output:  fmt.Sprintf("command not allowed: %s", cmdName),
-				Error:   nil,
-			}
-		}
+// runCommandAsync запускает конвейер в фоне и транслирует stdout последней
+// стадии и stderr всех стадий построчно. Конвейер уже проверен политикой;
+// timeout 0 — без ограничения.
+func runCommandAsync(p *pipeline, workingDir string, timeout time.Duration) (*commandRun, tea.Cmd) {
+	nextCommandID++
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	run := &commandRun{
+		ID:      nextCommandID,
+		Command: p.Source,
+		Started: time.Now(),
+		cancel:  cancel,
+		events:  make(chan tea.Msg, 256),
+	}
 
-		// Таймаут для команды — чтобы не зависала надолго
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	go func() {
+		defer close(run.events)
 		defer cancel()
 
-		cmd := exec.CommandContext(ctx, cmdName, args...)
-		cmd.Dir = workingDir
+		exit := func(code int, err error) {
+			run.events <- commandExitMsg{ID: run.ID, Code: code, Duration: time.Since(run.Started), Error: err}
+		}
 
-		output, err := cmd.CombinedOutput()
-		outStr := string(output)
+		// НЕ запускаем shell (sh -c) — конвейер собираем сами.
+		if len(p.Stages) == 0 {
+			exit(-1, fmt.Errorf("empty command"))
+			return
+		}
+		stdout, stdoutW, err := os.Pipe()
+		if err != nil {
+			exit(-1, err)
+			return
+		}
+		stderr, stderrW, err := os.Pipe()
+		if err != nil {
+			stdout.Close()
+			stdoutW.Close()
+			exit(-1, err)
+			return
+		}
+		cmds, err := startPipeline(ctx, p, workingDir, stdoutW, stderrW)
+		// Родителю концы для записи не нужны: иначе чтение не дождётся EOF
+		stdoutW.Close()
+		stderrW.Close()
+		if err != nil {
+			stdout.Close()
+			stderr.Close()
+			exit(-1, err)
+			return
+		}
 
-		// Если был таймаут, заменим ошибку понятной строкой
-		if ctx.Err() == context.DeadlineExceeded {
+		var wg sync.WaitGroup
+		pump := func(r io.Reader, isErr bool) {
+			defer wg.Done()
+			sc := bufio.NewScanner(r)
+			sc.Buffer(make([]byte, 64*1024), 1024*1024)
+			for sc.Scan() {
+				run.events <- commandOutputMsg{ID: run.ID, Line: sc.Text(), Stderr: isErr}
+			}
+		}
+		wg.Add(2)
+		go pump(stdout, false)
+		go pump(stderr, true)
+		wg.Wait()
+		stdout.Close()
+		stderr.Close()
+
+		// Код завершения конвейера — код последней стадии, как в sh
+		for _, c := range cmds {
+			err = c.Wait()
+		}
+		last := cmds[len(cmds)-1]
+		code := 0
+		if last.ProcessState != nil {
+			code = last.ProcessState.ExitCode()
+		}
+		switch {
+		case ctx.Err() == context.DeadlineExceeded:
+			// Если был таймаут, заменим ошибку понятной строкой
 			err = fmt.Errorf("command timed out")
+		case ctx.Err() == context.Canceled:
+			err = errCancelled
+		case code > 0:
+			// ненулевой код и так виден в строке завершения
+			err = nil
 		}
+		exit(code, err)
+	}()
 
-		// Ограничим вывод, чтобы не засорять память/интерфейс
-		const maxOut = 20000
-		if len(outStr) > maxOut {
-			outStr = outStr[:maxOut] + "\n...[output truncated]"
-		}
+	return run, run.wait()
+}
 
-		return runCommandMsg{
-			Command: command,
-			Output:  outStr,
-			Error:   err,
+// wait ждёт следующее событие команды: строку вывода или завершение.
+func (r *commandRun) wait() tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-r.events
+		if !ok {
+			return nil
 		}
+		return msg
 	}
 }
 
 func (m *model) refreshPanelsAfterChange(changedDir string) {
 	// Обновляем левую панель, если путь совпадает или вложен
 	if strings.HasPrefix(changedDir, m.leftDir) || changedDir == m.leftDir {
-		m.leftItems = getDirItems(m.leftDir, m.showHiddenLeft)
+		m.reloadPanel(0)
 	}
 	// Обновляем правую панель
 	if strings.HasPrefix(changedDir, m.rightDir) || changedDir == m.rightDir {
-		m.rightItems = getDirItems(m.rightDir, m.showHiddenRight)
+		m.reloadPanel(1)
 	}
 }
 
-func (m *model) adjustScroll() {
-	panelH := m.height - m.terminalHeight
-	if panelH < 3 {
-		panelH = 3
+// reloadPanel перечитывает содержимое панели: оставшиеся группы
+// дубликатов, пока панель в их корне, иначе обычный список каталога.
+// Цели ссылок запоминаются здесь же, чтобы отрисовка не обращалась к ФС.
+// Отметки сравнения описывают прежнее содержимое и сбрасываются.
+func (m *model) reloadPanel(panel int) {
+	m.compare = nil
+	if panel == 0 {
+		if m.leftDups != nil && m.leftDups.root != m.leftDir {
+			m.leftDups = nil
+		}
+		if m.leftDups != nil {
+			m.leftItems = m.leftDups.items()
+		} else {
+			m.leftItems = getDirItems(m.leftDir, m.showHiddenLeft)
+		}
+		m.leftLinks = panelLinks(m.leftDir, m.leftItems)
+		return
 	}
+	if m.rightDups != nil && m.rightDups.root != m.rightDir {
+		m.rightDups = nil
+	}
+	if m.rightDups != nil {
+		m.rightItems = m.rightDups.items()
+	} else {
+		m.rightItems = getDirItems(m.rightDir, m.showHiddenRight)
+	}
+	m.rightLinks = panelLinks(m.rightDir, m.rightItems)
+}
 
+// visibleRows — сколько элементов помещается в панели (как в renderPanel).
+func (m model) visibleRows() int {
+	_, panelH := m.panelSize()
 	maxVisible := panelH - 8
 	if maxVisible < 1 {
 		maxVisible = 1
 	}
+	return maxVisible
+}
 
-	if m.activePanel == 0 {
-		if m.leftCursor >= m.leftScroll+maxVisible {
-			m.leftScroll = m.leftCursor - maxVisible + 1
-		}
-	} else {
-		if m.rightCursor >= m.rightScroll+maxVisible {
-			m.rightScroll = m.rightCursor - maxVisible + 1
-		}
+// adjustScroll держит курсор обеих панелей в видимой области после
+// перемещения, изменения размера окна или высоты терминала.
+func (m *model) adjustScroll() {
+	maxVisible := m.visibleRows()
+	m.leftCursor, m.leftScroll = clampScroll(m.leftCursor, m.leftScroll, len(m.leftItems), maxVisible)
+	m.rightCursor, m.rightScroll = clampScroll(m.rightCursor, m.rightScroll, len(m.rightItems), maxVisible)
+}
+
+func clampScroll(cursor, scroll, n, visible int) (int, int) {
+	if cursor > n-1 {
+		cursor = n - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	if cursor < scroll {
+		scroll = cursor
+	}
+	if cursor >= scroll+visible {
+		scroll = cursor - visible + 1
+	}
+	// не оставляем пустой хвост, если после увеличения окна всё помещается
+	if scroll > n-visible {
+		scroll = n - visible
+	}
+	if scroll < 0 {
+		scroll = 0
 	}
+	return cursor, scroll
 }
 
 func (m model) View() string {
@@ -992,18 +1460,41 @@ func (m model) View() string {
 	if m.renaming {
 		return m.renderRenamePopup()
 	}
-
-	panelH := m.height - m.terminalHeight
-	if panelH < 1 {
-		panelH = 1
+	if m.confirm != nil {
+		return m.renderConfirm()
+	}
+	if m.syncWizard != nil {
+		return m.renderSyncWizard()
+	}
+	if m.openWith != nil {
+		return m.renderOpenWith()
+	}
+	if m.multiRename != nil {
+		return m.renderMultiRename()
+	}
+	if m.create != nil {
+		return m.renderCreate()
+	}
+	if m.links != nil {
+		return m.renderLinkDialog()
+	}
+	if m.props != nil {
+		return m.renderProps()
+	}
+	if m.pack != nil {
+		return m.renderPack()
+	}
+	if m.extract != nil {
+		return m.renderExtract()
 	}
-	panelW := m.width/2 - 2
-	if panelW < 10 {
-		panelW = m.width - 4
+	if m.dupsDialog != nil {
+		return m.renderDupsDialog()
 	}
 
-	left := renderPanel(m.leftDir, m.leftItems, m.selectedLeft, m.activePanel == 0 && !m.focusOnTerminal, panelW, panelH, m.leftCursor, m.leftScroll)
-	right := renderPanel(m.rightDir, m.rightItems, m.selectedRight, m.activePanel == 1 && !m.focusOnTerminal, panelW, panelH, m.rightCursor, m.rightScroll)
+	panelW, panelH := m.panelSize()
+
+	left := renderPanel(m.leftDir, m.leftItems, m.leftLinks, m.selectedLeft, m.panelDecor(0), m.activePanel == 0 && !m.focusOnTerminal, panelW, panelH, m.leftCursor, m.leftScroll)
+	right := renderPanel(m.rightDir, m.rightItems, m.rightLinks, m.selectedRight, m.panelDecor(1), m.activePanel == 1 && !m.focusOnTerminal, panelW, panelH, m.rightCursor, m.rightScroll)
 
 	var b strings.Builder
 	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, left, right))
@@ -1013,10 +1504,13 @@ func (m model) View() string {
 
 	if m.copying {
 		progress := fmt.Sprintf("Copying %s: %d%%", m.copyFile, m.copyPercent)
+		if m.syncRun != nil {
+			progress = fmt.Sprintf("Syncing %d/%d %s: %d%%", m.syncRun.index+1, len(m.syncRun.plan), m.copyFile, m.copyPercent)
+		}
 		b.WriteString("\n" + lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("214")).Render(progress))
 	}
 
-	b.WriteString("\n" + lipgloss.NewStyle().Faint(true).Render("Alt+←/→ switch panels • Alt+↑/↓ focus terminal • Ctrl+↑/↓ resize • Ctrl+T toggle terminal • q quit"))
+	b.WriteString("\n" + m.jobsIndicator() + lipgloss.NewStyle().Faint(true).Render("Alt+←/→ switch panels • Alt+↑/↓ focus terminal • Ctrl+↑/↓ resize • Ctrl+T toggle terminal • n/N new file/dir • T template • L link • F follow link • P properties • Z/X pack/extract • U duplicates • = compare • S sync • O open with • Ctrl+O shell • q quit"))
 	return b.String()
 }
 
@@ -1027,10 +1521,20 @@ func (m model) renderRenamePopup() string {
 		Padding(1, 2).
 		Width(50)
 
-	title := lipgloss.NewStyle().Bold(true).Render("Rename file")
+	title := lipgloss.NewStyle().Bold(true).Render("Rename " + filepath.Base(m.renameOldPath))
 	inputView := m.renameInput.View()
+	if m.renameStemSelected {
+		// textinput не умеет выделение — рисуем его сами
+		stem, ext := m.renameStem()
+		inputView = m.renameInput.Prompt + lipgloss.NewStyle().Reverse(true).Render(stem) + ext
+	}
 
-	content := lipgloss.JoinVertical(lipgloss.Left, title, inputView)
+	lines := []string{title, inputView}
+	if m.renameErr != "" {
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(m.renameErr))
+	}
+	lines = append(lines, lipgloss.NewStyle().Faint(true).Render("Enter rename • Esc cancel"))
+	content := lipgloss.JoinVertical(lipgloss.Left, lines...)
 	popup := popupStyle.Render(content)
 
 	popupWidth := 50
@@ -1047,6 +1551,9 @@ func (m model) renderRenamePopup() string {
 }
 
 func getDirItems(dir string, showHidden bool) []string {
+	if archive, inner, ok := splitArchivePath(dir); ok {
+		return archiveDirItems(archive, inner, showHidden)
+	}
 	files, err := os.ReadDir(dir)
 	if err != nil {
 		return []string{"Error: " + err.Error()}
@@ -1062,7 +1569,28 @@ func getDirItems(dir string, showHidden bool) []string {
 	return items
 }
 
-func renderPanel(dir string, items []string, selected map[string]bool, active bool, w, h int, cursor int, scroll int) string {
+// panelDecor — отметки панели поверх списка: результаты сравнения или
+// группы дубликатов.
+type panelDecor struct {
+	tag  func(item string) string // метка перед именем; nil — без меток
+	note string                   // пояснение к заголовку
+}
+
+func (m model) panelDecor(panel int) panelDecor {
+	dups := m.leftDups
+	if panel == 1 {
+		dups = m.rightDups
+	}
+	if dups != nil {
+		return panelDecor{tag: dups.dupTag, note: dups.note()}
+	}
+	if marks := m.compareMarks(panel); marks != nil {
+		return panelDecor{tag: func(item string) string { return compareTag(marks[item]) }}
+	}
+	return panelDecor{}
+}
+
+func renderPanel(dir string, items []string, links map[string]panelLink, selected map[string]bool, decor panelDecor, active bool, w, h int, cursor int, scroll int) string {
 	if w < 10 {
 		w = 10
 	}
@@ -1100,11 +1628,31 @@ func renderPanel(dir string, items []string, selected map[string]bool, active bo
 	}
 
 	title := lipgloss.NewStyle().Bold(true).Render(filepath.Base(dir))
+	if isArchiveDir(dir) {
+		title += lipgloss.NewStyle().Faint(true).Render(" (archive, read-only)")
+	}
+	if decor.note != "" {
+		title += lipgloss.NewStyle().Faint(true).Render(decor.note)
+	}
 
 	var body strings.Builder
 	for i, item := range visibleItems {
 		index := scroll + i
 		isSelected := selected[item]
+		if decor.tag != nil {
+			body.WriteString(decor.tag(item))
+		}
+
+		// Имя для показа: у ссылок — вместе с целью
+		label := item
+		link, isLink := links[item]
+		broken := isLink && link.broken
+		if isLink {
+			label += " → " + link.target
+			if broken {
+				label += " (broken)"
+			}
+		}
 
 		if index == cursor {
 			if isSelected {
@@ -1113,14 +1661,14 @@ func renderPanel(dir string, items []string, selected map[string]bool, active bo
 						Foreground(lipgloss.Color("0")).
 						Background(lipgloss.Color("213")).
 						Bold(true).
-						Render("[*] " + item),
+						Render("[*] " + label),
 				)
 			} else {
 				body.WriteString(
 					lipgloss.NewStyle().
 						Foreground(lipgloss.Color("171")).
 						Bold(true).
-						Render("● " + item),
+						Render("● " + label),
 				)
 			}
 		} else {
@@ -1128,10 +1676,14 @@ func renderPanel(dir string, items []string, selected map[string]bool, active bo
 				body.WriteString(
 					lipgloss.NewStyle().
 						Foreground(lipgloss.Color("213")).
-						Render("[*] " + item),
+						Render("[*] " + label),
 				)
 			} else {
-				body.WriteString("   " + item)
+				line := "   " + label
+				if broken {
+					line = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(line)
+				}
+				body.WriteString(line)
 			}
 		}
 		body.WriteString("\n")
@@ -1159,18 +1711,41 @@ func renderTerminal(m model, h, w int) string {
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(borderColor)
 
+	if m.shellMode && m.shell != nil {
+		lines := m.shell.screen.Lines(m.focusOnTerminal)
+		if len(lines) > h {
+			lines = lines[len(lines)-h:]
+		}
+		return box.PaddingLeft(1).Render(strings.Join(lines, "\n"))
+	}
+
 	maxLines := h - 3
 	if maxLines < 0 {
 		maxLines = 0
 	}
 
-	outLines := m.termOutput
-	if len(outLines) > maxLines {
-		outLines = outLines[len(outLines)-maxLines:]
+	var menuLines []string
+	if m.completion != nil {
+		menuLines = m.completion.render(min(5, maxLines), w-8)
+		maxLines -= len(menuLines)
 	}
-	out := strings.Join(outLines, "\n")
+
+	outLines := m.termOutput.render(maxLines)
+	out := strings.Join(append(outLines, menuLines...), "\n")
 
 	inputView := m.termInput.View()
+	if m.history.search != nil {
+		inputView = m.history.searchPrompt()
+	}
+	if m.termOutput.search != nil {
+		inputView = m.termOutput.searchPrompt()
+	} else if m.termOutput.offset > 0 {
+		inputView = lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("[↑%d] ", m.termOutput.offset)) + inputView
+	}
+	if m.history.dirOnly {
+		inputView = lipgloss.NewStyle().Faint(true).Render("[dir] ") + inputView
+	}
+	inputView = m.jobsIndicator() + inputView
 	if m.focusOnTerminal {
 		inputView = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212")).Render(inputView)
 	}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// doubleClickInterval — максимальная пауза между кликами двойного клика.
+const doubleClickInterval = 400 * time.Millisecond
+
+// mouseState хранит состояние, нужное для двойного клика и перетаскивания границы.
+type mouseState struct {
+	lastClick      time.Time
+	lastClickPanel int
+	lastClickIndex int
+	resizing       bool
+}
+
+// panelSize возвращает ширину и высоту панелей так же, как их считает View.
+func (m model) panelSize() (int, int) {
+	panelH := m.height - m.terminalHeight
+	if panelH < 1 {
+		panelH = 1
+	}
+	panelW := m.width/2 - 2
+	if panelW < 10 {
+		panelW = m.width - 4
+	}
+	return panelW, panelH
+}
+
+// terminalTop — строка верхней границы терминала или -1, если терминал скрыт.
+func (m model) terminalTop() int {
+	if m.terminalMode == TermBottomHidden || m.terminalHeight <= 0 {
+		return -1
+	}
+	_, panelH := m.panelSize()
+	// Рамка панели занимает panelH-4 строк, терминал идёт сразу под ней.
+	return panelH - 4
+}
+
+// panelAt определяет панель и индекс элемента под точкой (x, y).
+// Индекс равен -1, если точка попала на рамку или заголовок.
+func (m model) panelAt(x, y int) (panel int, index int, ok bool) {
+	panelW, panelH := m.panelSize()
+	if y < 0 || y >= panelH-4 {
+		return 0, -1, false
+	}
+	panel = 0
+	if x >= panelW+2 {
+		panel = 1
+	}
+	if x >= 2*(panelW+2) {
+		return 0, -1, false
+	}
+
+	index = -1
+	row := y - 2 // рамка и заголовок
+	if row >= 0 && row < panelH-8 {
+		items, scroll := m.leftItems, m.leftScroll
+		if panel == 1 {
+			items, scroll = m.rightItems, m.rightScroll
+		}
+		if i := scroll + row; i < len(items) {
+			index = i
+		}
+	}
+	return panel, index, true
+}
+
+func (m *model) focusPanel(panel int) {
+	m.activePanel = panel
+	m.focusOnTerminal = false
+	m.termInput.Blur()
+}
+
+func (m model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	// Пока открыт диалог, панели мышью не управляются
+	if m.renaming || m.syncWizard != nil || m.openWith != nil || m.confirm != nil || m.multiRename != nil || m.create != nil || m.links != nil || m.props != nil || m.pack != nil || m.extract != nil || m.dupsDialog != nil {
+		return m, nil
+	}
+
+	var cmds []tea.Cmd
+	top := m.terminalTop()
+
+	// Перетаскивание границы между панелями и терминалом
+	if m.mouse.resizing {
+		switch msg.Action {
+		case tea.MouseActionMotion:
+			h := m.height - 4 - msg.Y
+			if h < 1 {
+				h = 1
+			}
+			if h > m.height-3 {
+				h = m.height - 3
+			}
+			m.terminalHeight = h
+			m.targetTermHeight = h
+			m.termAnimating = false
+			m.adjustScroll()
+		case tea.MouseActionRelease:
+			m.mouse.resizing = false
+		}
+		return m, nil
+	}
+
+	inTerminal := top >= 0 && msg.Y > top && msg.Y <= top+m.terminalHeight+1
+
+	switch msg.Button {
+	case tea.MouseButtonWheelUp, tea.MouseButtonWheelDown:
+		delta := 3
+		if msg.Button == tea.MouseButtonWheelUp {
+			delta = -3
+		}
+		if inTerminal {
+			m.scrollTerminal(-delta)
+			return m, nil
+		}
+		if panel, _, ok := m.panelAt(msg.X, msg.Y); ok {
+			m.activePanel = panel
+			m.moveCursor(delta)
+		}
+		return m, nil
+
+	case tea.MouseButtonLeft:
+		if msg.Action != tea.MouseActionPress {
+			return m, nil
+		}
+		// Граница: нижняя рамка панелей или верхняя рамка терминала
+		if top >= 0 && (msg.Y == top || msg.Y == top-1) {
+			m.mouse.resizing = true
+			return m, nil
+		}
+		if inTerminal {
+			m.focusOnTerminal = true
+			cmds = append(cmds, m.termInput.Focus())
+			return m, tea.Batch(cmds...)
+		}
+
+		panel, index, ok := m.panelAt(msg.X, msg.Y)
+		if !ok {
+			return m, nil
+		}
+		m.focusPanel(panel)
+		if index < 0 {
+			return m, nil
+		}
+		m.setCursor(index)
+
+		if msg.Ctrl {
+			m.toggleSelection()
+			return m, nil
+		}
+
+		now := time.Now()
+		double := now.Sub(m.mouse.lastClick) < doubleClickInterval &&
+			m.mouse.lastClickPanel == panel && m.mouse.lastClickIndex == index
+		m.mouse.lastClick, m.mouse.lastClickPanel, m.mouse.lastClickIndex = now, panel, index
+		if double {
+			m.mouse.lastClick = time.Time{}
+			return m.Update(tea.KeyMsg{Type: tea.KeyRight})
+		}
+	}
+
+	return m, nil
+}
+
+// setCursor ставит курсор активной панели на элемент index.
+func (m *model) setCursor(index int) {
+	if m.activePanel == 0 {
+		m.leftCursor = index
+	} else {
+		m.rightCursor = index
+	}
+	m.adjustScroll()
+}
+
+// moveCursor сдвигает курсор активной панели на delta элементов.
+func (m *model) moveCursor(delta int) {
+	cursor, n := m.leftCursor, len(m.leftItems)
+	if m.activePanel == 1 {
+		cursor, n = m.rightCursor, len(m.rightItems)
+	}
+	cursor += delta
+	if cursor > n-1 {
+		cursor = n - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	m.setCursor(cursor)
+}
+
+// toggleSelection переключает выделение элемента под курсором активной панели.
+func (m *model) toggleSelection() {
+	items, cursor, selected := m.leftItems, m.leftCursor, m.selectedLeft
+	if m.activePanel == 1 {
+		items, cursor, selected = m.rightItems, m.rightCursor, m.selectedRight
+	}
+	if len(items) == 0 {
+		return
+	}
+	name := items[cursor]
+	if selected[name] {
+		delete(selected, name)
+	} else {
+		selected[name] = true
+	}
+}
+
+// scrollTerminal прокручивает вывод терминала; положительный delta — вверх, к старым строкам.
+func (m *model) scrollTerminal(delta int) {
+	m.termOutput.scroll(delta)
+}
+
+// termOutputRows — сколько строк вывода помещается в терминале.
+func (m model) termOutputRows() int {
+	return max(1, m.terminalHeight-3)
+}
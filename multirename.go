@@ -0,0 +1,457 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Поля диалога группового переименования, в порядке показа.
+const (
+	mrTemplate = iota
+	mrFind
+	mrReplace
+	mrCase
+	mrExt
+	mrPos
+	mrStrip
+	mrInsert
+	mrStart
+	mrFieldCount
+)
+
+type caseMode int
+
+const (
+	caseKeep caseMode = iota
+	caseLower
+	caseUpper
+	caseTitle
+)
+
+func (c caseMode) String() string {
+	switch c {
+	case caseLower:
+		return "lower"
+	case caseUpper:
+		return "UPPER"
+	case caseTitle:
+		return "Title"
+	}
+	return "keep"
+}
+
+// renameEntry — исходный элемент и его mtime для токена {date}.
+type renameEntry struct {
+	name  string
+	mtime time.Time
+}
+
+// renamePreview — строка предпросмотра: новое имя и проблема, если есть.
+type renamePreview struct {
+	pair    renamePair
+	problem string
+}
+
+// multiRename — диалог переименования выделения по правилам.
+type multiRename struct {
+	dir     string
+	entries []renameEntry
+	inputs  [mrFieldCount]textinput.Model // для mrCase не используется
+	caseM   caseMode
+	field   int
+
+	preview []renamePreview
+	err     error // ошибка в правилах (регулярка, шаблон)
+	scroll  int
+}
+
+// renameBatch — последнее групповое переименование, для отмены клавишей u.
+type renameBatch struct {
+	dir   string
+	pairs []renamePair
+}
+
+var mrLabels = [mrFieldCount]string{
+	mrTemplate: "Name template:",
+	mrFind:     "Find (regex):",
+	mrReplace:  "Replace with:",
+	mrCase:     "Case:",
+	mrExt:      "Extension:",
+	mrPos:      "Position:",
+	mrStrip:    "Strip chars:",
+	mrInsert:   "Insert text:",
+	mrStart:    "Counter start:",
+}
+
+// openMultiRename открывает диалог для выделения (или элемента под курсором).
+func (m *model) openMultiRename() {
+	if m.refuseInArchive("Rename") {
+		return
+	}
+	dir := m.activeDir()
+	names := m.activeSelection()
+	if len(names) == 0 {
+		items := m.activeItems()
+		cursor := m.leftCursor
+		if m.activePanel == 1 {
+			cursor = m.rightCursor
+		}
+		if len(items) == 0 {
+			return
+		}
+		names = []string{items[cursor]}
+	}
+
+	w := &multiRename{dir: dir}
+	for _, name := range names {
+		e := renameEntry{name: name}
+		if info, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+			e.mtime = info.ModTime()
+		}
+		w.entries = append(w.entries, e)
+	}
+	for i := range w.inputs {
+		ti := textinput.New()
+		ti.Prompt = ""
+		ti.CharLimit = 256
+		ti.Width = 40
+		w.inputs[i] = ti
+	}
+	w.inputs[mrTemplate].SetValue("{name}{ext}")
+	w.inputs[mrTemplate].Focus()
+	w.inputs[mrPos].Placeholder = "0 (negative — from the end)"
+	w.inputs[mrExt].Placeholder = "keep (. removes)"
+	w.inputs[mrStart].Placeholder = "1"
+	m.multiRename = w
+	w.update()
+}
+
+// splitExt делит имя на основу и расширение с точкой; у ".bashrc" расширения нет.
+func splitExt(name string) (string, string) {
+	ext := filepath.Ext(name)
+	if ext == name {
+		return name, ""
+	}
+	return strings.TrimSuffix(name, ext), ext
+}
+
+var templateToken = regexp.MustCompile(`\{(\w+)(?::([^}]*))?\}`)
+
+// dateLayout переводит YYYY-MM-DD hh:mm:ss в раскладку time.Format.
+var dateLayout = strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02", "hh", "15", "mm", "04", "ss", "05")
+
+// expandTemplate подставляет {name}, {ext}, {n[:03]} и {date[:YYYYMMDD]}.
+func expandTemplate(tmpl string, e renameEntry, n int) (string, error) {
+	stem, ext := splitExt(e.name)
+	var err error
+	out := templateToken.ReplaceAllStringFunc(tmpl, func(tok string) string {
+		sub := templateToken.FindStringSubmatch(tok)
+		switch sub[1] {
+		case "name":
+			return stem
+		case "ext":
+			return ext
+		case "n":
+			if sub[2] == "" {
+				return strconv.Itoa(n)
+			}
+			width, convErr := strconv.Atoi(sub[2])
+			if convErr != nil {
+				err = fmt.Errorf("bad counter width in %s", tok)
+				return tok
+			}
+			if strings.HasPrefix(sub[2], "0") {
+				return fmt.Sprintf("%0*d", width, n)
+			}
+			return fmt.Sprintf("%*d", width, n)
+		case "date":
+			layout := "YYYY-MM-DD"
+			if sub[2] != "" {
+				layout = sub[2]
+			}
+			return e.mtime.Format(dateLayout.Replace(layout))
+		}
+		err = fmt.Errorf("unknown token %s", tok)
+		return tok
+	})
+	return out, err
+}
+
+// applyCase меняет регистр основы имени.
+func applyCase(s string, c caseMode) string {
+	switch c {
+	case caseLower:
+		return strings.ToLower(s)
+	case caseUpper:
+		return strings.ToUpper(s)
+	case caseTitle:
+		runes := []rune(strings.ToLower(s))
+		start := true
+		for i, r := range runes {
+			if start && unicode.IsLetter(r) {
+				runes[i] = unicode.ToUpper(r)
+			}
+			start = !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}
+		return string(runes)
+	}
+	return s
+}
+
+// newName применяет правила по порядку: шаблон, замена по регулярке,
+// вырезание и вставка в основе имени, регистр, расширение.
+func (w *multiRename) newName(re *regexp.Regexp, e renameEntry, n int) (string, error) {
+	name, err := expandTemplate(w.inputs[mrTemplate].Value(), e, n)
+	if err != nil {
+		return "", err
+	}
+	if re != nil {
+		name = re.ReplaceAllString(name, w.inputs[mrReplace].Value())
+	}
+
+	stem, ext := splitExt(name)
+	runes := []rune(stem)
+	pos, _ := strconv.Atoi(w.inputs[mrPos].Value())
+	if pos < 0 {
+		pos += len(runes)
+	}
+	pos = max(0, min(pos, len(runes)))
+	strip, _ := strconv.Atoi(w.inputs[mrStrip].Value())
+	end := max(pos, min(pos+strip, len(runes)))
+	insert := []rune(w.inputs[mrInsert].Value())
+	runes = append(runes[:pos:pos], append(insert, runes[end:]...)...)
+	stem = applyCase(string(runes), w.caseM)
+
+	switch newExt := w.inputs[mrExt].Value(); {
+	case newExt == ".":
+		ext = ""
+	case newExt != "":
+		ext = "." + strings.TrimPrefix(newExt, ".")
+	}
+	return stem + ext, nil
+}
+
+// update пересчитывает предпросмотр после каждого изменения правил.
+func (w *multiRename) update() {
+	w.preview, w.err = nil, nil
+	var re *regexp.Regexp
+	if find := w.inputs[mrFind].Value(); find != "" {
+		var err error
+		if re, err = regexp.Compile(find); err != nil {
+			w.err = err
+			return
+		}
+	}
+	start := 1
+	if v := w.inputs[mrStart].Value(); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			w.err = fmt.Errorf("counter start must be a number")
+			return
+		}
+		start = n
+	}
+	for _, f := range []int{mrPos, mrStrip} {
+		if v := w.inputs[f].Value(); v != "" {
+			if _, err := strconv.Atoi(v); err != nil {
+				w.err = fmt.Errorf("%s must be a number", strings.TrimSuffix(mrLabels[f], ":"))
+				return
+			}
+		}
+	}
+
+	var pairs []renamePair
+	for i, e := range w.entries {
+		name, err := w.newName(re, e, start+i)
+		if err != nil {
+			w.err = err
+			return
+		}
+		p := renamePair{Old: e.name, New: name}
+		w.preview = append(w.preview, renamePreview{pair: p})
+		if name != e.name {
+			pairs = append(pairs, p)
+		}
+	}
+	problems := renameProblems(w.dir, pairs)
+	for i := range w.preview {
+		if w.preview[i].pair.Old != w.preview[i].pair.New {
+			w.preview[i].problem = problems[w.preview[i].pair.Old]
+		}
+	}
+}
+
+// changes — переименования из предпросмотра и число конфликтов.
+func (w *multiRename) changes() ([]renamePair, int) {
+	var pairs []renamePair
+	conflicts := 0
+	for _, p := range w.preview {
+		if p.problem != "" {
+			conflicts++
+		}
+		if p.pair.Old != p.pair.New {
+			pairs = append(pairs, p.pair)
+		}
+	}
+	return pairs, conflicts
+}
+
+func (w *multiRename) focus(field int) {
+	w.inputs[w.field].Blur()
+	w.field = (field + mrFieldCount) % mrFieldCount
+	w.inputs[w.field].Focus()
+}
+
+// previewRows — сколько строк предпросмотра помещается в окно.
+func (m model) previewRows() int {
+	return max(3, m.height-mrFieldCount-14)
+}
+
+func (m model) updateMultiRename(msg tea.KeyMsg) (model, tea.Cmd) {
+	w := m.multiRename
+	switch msg.String() {
+	case "esc":
+		m.multiRename = nil
+		return m, nil
+	case "up", "shift+tab":
+		w.focus(w.field - 1)
+		return m, nil
+	case "down", "tab":
+		w.focus(w.field + 1)
+		return m, nil
+	case "pgup":
+		w.scroll = max(0, w.scroll-m.previewRows())
+		return m, nil
+	case "pgdown":
+		w.scroll = max(0, min(w.scroll+m.previewRows(), len(w.preview)-m.previewRows()))
+		return m, nil
+	case "enter":
+		pairs, conflicts := w.changes()
+		if w.err != nil || conflicts > 0 {
+			return m, nil
+		}
+		m.multiRename = nil
+		if len(pairs) == 0 {
+			m.termOutput.add("Multi-rename: nothing to change.")
+			return m, nil
+		}
+		m.applyRenamePlan("Multi-rename", w.dir, pairs)
+		return m, nil
+	}
+
+	if w.field == mrCase {
+		switch msg.String() {
+		case "left":
+			w.caseM = (w.caseM + 3) % 4
+		case "right", " ":
+			w.caseM = (w.caseM + 1) % 4
+		}
+		w.update()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	w.inputs[w.field], cmd = w.inputs[w.field].Update(msg)
+	w.update()
+	return m, cmd
+}
+
+func (m model) renderMultiRename() string {
+	w := m.multiRename
+	popupWidth := max(40, min(m.width-10, 110))
+	popupStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("171")).
+		Padding(1, 2).
+		Width(popupWidth)
+	hint := lipgloss.NewStyle().Faint(true)
+	bad := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	title := lipgloss.NewStyle().Bold(true).Render(fmt.Sprintf("Multi-rename %d entries in %s", len(w.entries), filepath.Base(w.dir)))
+
+	var b strings.Builder
+	for i := 0; i < mrFieldCount; i++ {
+		value := w.inputs[i].View()
+		if i == mrCase {
+			value = "‹ " + w.caseM.String() + " ›"
+		}
+		line := fmt.Sprintf("%-15s %s", mrLabels[i], value)
+		if i == w.field {
+			b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("171")).Bold(true).Render("● ") + line)
+		} else {
+			b.WriteString("  " + line)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(hint.Render("Tokens: {name} {ext} {n} {n:03} {date} {date:YYYYMMDD-hhmmss} • replace: $1 for groups") + "\n\n")
+
+	pairs, conflicts := w.changes()
+	if w.err != nil {
+		b.WriteString(bad.Render("Error: "+w.err.Error()) + "\n")
+	} else {
+		colW := 0
+		for _, p := range w.preview {
+			colW = max(colW, lipgloss.Width(p.pair.Old))
+		}
+		colW = min(colW, (popupWidth-10)/2)
+		end := min(w.scroll+m.previewRows(), len(w.preview))
+		for _, p := range w.preview[w.scroll:end] {
+			old := p.pair.Old
+			if lipgloss.Width(old) > colW {
+				old = string([]rune(old)[:max(colW-1, 0)]) + "…"
+			}
+			line := fmt.Sprintf("%-*s → %s", colW, old, p.pair.New)
+			switch {
+			case p.problem != "":
+				line = bad.Render(line + "  ✗ " + p.problem)
+			case p.pair.Old == p.pair.New:
+				line = hint.Render(line)
+			}
+			b.WriteString(line + "\n")
+		}
+		if end < len(w.preview) {
+			b.WriteString(hint.Render(fmt.Sprintf("… %d more (PgUp/PgDn)", len(w.preview)-end)) + "\n")
+		}
+		status := fmt.Sprintf("%d to rename", len(pairs))
+		if conflicts > 0 {
+			status = bad.Render(fmt.Sprintf("%d to rename, %d conflicts", len(pairs), conflicts))
+		}
+		b.WriteString("\n" + status + "\n")
+	}
+	b.WriteString("\n" + hint.Render("↑/↓ field • ←/→/Space case • Enter apply • Esc cancel • u in panel undoes"))
+
+	popup := popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, title, "", b.String()))
+	x := max(0, (m.width-popupWidth)/2)
+	return lipgloss.NewStyle().MarginLeft(x).MarginTop(1).Render(popup)
+}
+
+// undoRename возвращает старые имена последнему групповому переименованию.
+func (m *model) undoRename() {
+	batch := m.lastRename
+	if batch == nil {
+		m.termOutput.add("Nothing to undo.")
+		return
+	}
+	var pairs []renamePair
+	for i := len(batch.pairs) - 1; i >= 0; i-- {
+		p := batch.pairs[i]
+		pairs = append(pairs, renamePair{Old: p.New, New: p.Old})
+	}
+	if problems := checkRenamePlan(batch.dir, pairs); len(problems) > 0 {
+		m.termOutput.add("Undo rename: conflicts, nothing renamed:")
+		m.termOutput.add(problems...)
+		return
+	}
+	m.applyRenamePlan("Undo rename", batch.dir, pairs)
+	// Отмена отмены не предусмотрена
+	m.lastRename = nil
+}
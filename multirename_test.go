@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	e := renameEntry{name: "photo.final.JPG", mtime: time.Date(2024, 3, 7, 9, 5, 2, 0, time.Local)}
+	tests := []struct {
+		tmpl string
+		n    int
+		want string
+	}{
+		{"{name}{ext}", 1, "photo.final.JPG"},
+		{"{n}_{name}", 7, "7_photo.final"},
+		{"{n:03}{ext}", 7, "007.JPG"},
+		{"{n:3}", 42, " 42"},
+		{"{date}", 1, "2024-03-07"},
+		{"{date:YYYYMMDD_hhmmss}", 1, "20240307_090502"},
+		{"{date:YY-MM}-{name}", 1, "24-03-photo.final"},
+		{"plain", 1, "plain"},
+	}
+	for _, tt := range tests {
+		got, err := expandTemplate(tt.tmpl, e, tt.n)
+		if err != nil {
+			t.Errorf("expandTemplate(%q): %v", tt.tmpl, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("expandTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+
+	for _, tmpl := range []string{"{size}", "{n:x}", "{name}{bogus:1}"} {
+		if _, err := expandTemplate(tmpl, e, 1); err == nil {
+			t.Errorf("expandTemplate(%q): expected an error", tmpl)
+		}
+	}
+}
+
+func TestSplitExt(t *testing.T) {
+	tests := []struct{ name, stem, ext string }{
+		{"a.txt", "a", ".txt"},
+		{"a.tar.gz", "a.tar", ".gz"},
+		{"noext", "noext", ""},
+		{".bashrc", ".bashrc", ""},
+	}
+	for _, tt := range tests {
+		if stem, ext := splitExt(tt.name); stem != tt.stem || ext != tt.ext {
+			t.Errorf("splitExt(%q) = %q, %q, want %q, %q", tt.name, stem, ext, tt.stem, tt.ext)
+		}
+	}
+}
+
+func TestApplyCase(t *testing.T) {
+	tests := []struct {
+		in   string
+		c    caseMode
+		want string
+	}{
+		{"MiXed", caseKeep, "MiXed"},
+		{"MiXed", caseLower, "mixed"},
+		{"MiXed", caseUpper, "MIXED"},
+		{"hello wORLD-foo_bar", caseTitle, "Hello World-Foo_Bar"},
+		{"привет мир", caseTitle, "Привет Мир"},
+	}
+	for _, tt := range tests {
+		if got := applyCase(tt.in, tt.c); got != tt.want {
+			t.Errorf("applyCase(%q, %v) = %q, want %q", tt.in, tt.c, got, tt.want)
+		}
+	}
+}
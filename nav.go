@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// takeCount возвращает накопленный счётчик (по умолчанию 1) и сбрасывает его.
+func (m *model) takeCount() int {
+	n := m.countPrefix
+	m.countPrefix = 0
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// jumpToLetter переводит курсор на следующий элемент, начинающийся с буквы r
+// (без учёта регистра), с переходом через конец списка.
+func (m *model) jumpToLetter(r rune) {
+	items, cursor := m.leftItems, m.leftCursor
+	if m.activePanel == 1 {
+		items, cursor = m.rightItems, m.rightCursor
+	}
+	prefix := string(unicode.ToLower(r))
+	for i := 1; i <= len(items); i++ {
+		idx := (cursor + i) % len(items)
+		if strings.HasPrefix(strings.ToLower(items[idx]), prefix) {
+			m.setCursor(idx)
+			return
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// fileOwner: владельцев в смысле unix на этой платформе нет.
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Поля окна упаковки.
+const (
+	packName = iota
+	packFormat
+	packLevel
+	packFieldCount
+)
+
+// packDialog — упаковка выделения активной панели в архив в каталоге
+// другой панели.
+type packDialog struct {
+	srcDir string
+	dstDir string
+	names  []string
+	field  int
+	format int // индекс в archiveFormats
+	level  int
+	input  textinput.Model
+	err    string
+}
+
+func (m *model) openPack() tea.Cmd {
+	names := m.activeSelection()
+	if len(names) == 0 {
+		items, cursor := m.activeItems(), m.leftCursor
+		if m.activePanel == 1 {
+			cursor = m.rightCursor
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		names = []string{items[cursor]}
+	}
+	d := &packDialog{srcDir: m.activeDir(), dstDir: m.rightDir, names: names, format: 2, level: defaultArchiveLevel}
+	if m.activePanel == 1 {
+		d.dstDir = m.leftDir
+	}
+	base := filepath.Base(d.srcDir)
+	if len(names) == 1 {
+		base = names[0]
+	}
+	d.input = textinput.New()
+	d.input.Prompt = ""
+	d.input.CharLimit = 255
+	d.input.Width = 40
+	d.input.SetValue(base + archiveFormats[d.format].exts[0])
+	m.pack = d
+	return d.input.Focus()
+}
+
+// setFormat меняет формат и расширение в имени архива.
+func (d *packDialog) setFormat(format int) {
+	n := len(archiveFormats)
+	d.format = (format + n) % n
+	d.input.SetValue(stripArchiveExt(d.input.Value()) + archiveFormats[d.format].exts[0])
+	d.input.CursorEnd()
+}
+
+func (m model) updatePack(msg tea.KeyMsg) (model, tea.Cmd) {
+	d := m.pack
+	switch msg.String() {
+	case "esc":
+		m.pack = nil
+		return m, nil
+	case "tab", "shift+tab":
+		step := 1
+		if msg.String() == "shift+tab" {
+			step = packFieldCount - 1
+		}
+		d.field = (d.field + step) % packFieldCount
+		if d.field == packName {
+			return m, d.input.Focus()
+		}
+		d.input.Blur()
+		return m, nil
+	case "enter":
+		name := d.input.Value()
+		if err := validName(name); err != nil {
+			d.err = err.Error()
+			return m, nil
+		}
+		dst := filepath.Join(d.dstDir, name)
+		if _, err := os.Lstat(dst); err == nil {
+			d.err = name + " already exists in " + d.dstDir
+			return m, nil
+		}
+		m.pack = nil
+		format, level := d.format, d.level
+		srcDir, names := d.srcDir, d.names
+		cmd := m.startTask("pack "+dst, func(ctx context.Context, out taskOutput) error {
+			return packArchive(ctx, srcDir, names, dst, format, level, out)
+		})
+		return m, cmd
+	}
+	d.err = ""
+
+	switch d.field {
+	case packFormat:
+		switch msg.String() {
+		case "left", "h":
+			d.setFormat(d.format - 1)
+		case "right", "l", " ":
+			d.setFormat(d.format + 1)
+		}
+		return m, nil
+	case packLevel:
+		switch msg.String() {
+		case "left", "h", "-":
+			d.level = max(1, d.level-1)
+		case "right", "l", "+":
+			d.level = min(9, d.level+1)
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	d.input, cmd = d.input.Update(msg)
+	return m, cmd
+}
+
+func (m model) renderPack() string {
+	d := m.pack
+	popupWidth := 64
+	popupStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("171")).
+		Padding(1, 2).
+		Width(popupWidth)
+	hint := lipgloss.NewStyle().Faint(true)
+
+	what := d.names[0]
+	if len(d.names) > 1 {
+		what = fmt.Sprintf("%d entries", len(d.names))
+	}
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Render("Pack " + what + " into " + d.dstDir),
+		"",
+	}
+	level := "‹ " + fmt.Sprint(d.level) + " › (1 fastest … 9 smallest)"
+	if !archiveFormats[d.format].leveled {
+		level = hint.Render("no compression")
+	}
+	values := [packFieldCount]string{d.input.View(), "‹ " + archiveFormats[d.format].name + " ›", level}
+	labels := [packFieldCount]string{"Name", "Format", "Level"}
+	for i := 0; i < packFieldCount; i++ {
+		marker := "  "
+		if i == d.field {
+			marker = lipgloss.NewStyle().Foreground(lipgloss.Color("171")).Bold(true).Render("● ")
+		}
+		lines = append(lines, marker+fmt.Sprintf("%-7s %s", labels[i], values[i]))
+	}
+	if d.err != "" {
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(d.err))
+	}
+	lines = append(lines, "", hint.Render("Tab field • ←/→ change • Enter pack • Esc cancel"))
+
+	popup := popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	x := (m.width - popupWidth) / 2
+	y := (m.height - lipgloss.Height(popup)) / 2
+	if y < 0 {
+		y = 0
+	}
+	return lipgloss.NewStyle().MarginLeft(x).MarginTop(y).Render(popup)
+}
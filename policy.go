@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const defaultCommandTimeout = 30 * time.Second
+
+type policyAction string
+
+const (
+	policyAllow policyAction = "allow"
+	policyAsk   policyAction = "ask"
+	policyBlock policyAction = "block"
+)
+
+// policyRule — правило для команды. Правило применяется, если совпали имя
+// команды, каталог и ограничения на аргументы; срабатывает первое подходящее.
+type policyRule struct {
+	Command string       `json:"command"`          // glob по имени команды
+	Action  policyAction `json:"action"`           // allow, ask или block
+	Args    []string     `json:"args,omitempty"`   // каждый аргумент должен подойти под один из шаблонов
+	AnyArg  []string     `json:"anyArg,omitempty"` // хотя бы один аргумент должен подойти
+	Dirs    []string     `json:"dirs,omitempty"`   // разрешённые рабочие каталоги (glob, "/**" — поддерево)
+	Timeout string       `json:"timeout,omitempty"`
+}
+
+// commandPolicy — содержимое файла политики.
+type commandPolicy struct {
+	Default policyAction `json:"default"` // для команд без подходящего правила: ask или block
+	Rules   []policyRule `json:"rules"`
+}
+
+// policyDecision — результат проверки команды.
+type policyDecision struct {
+	Action  policyAction
+	Timeout time.Duration // 0 — без ограничения
+	Reason  string
+	rule    *policyRule // сработавшее правило; nil — политика по умолчанию
+}
+
+// defaultPolicy повторяет прежний встроенный белый список.
+func defaultPolicy() *commandPolicy {
+	p := &commandPolicy{Default: policyBlock}
+	for _, name := range []string{"ls", "pwd", "cat", "echo", "head", "tail", "stat", "date"} {
+		p.Rules = append(p.Rules, policyRule{Command: name, Action: policyAllow})
+	}
+	// Плеер работает сколько угодно долго
+	p.Rules = append(p.Rules, policyRule{Command: "mpv", Action: policyAllow, Timeout: "none"})
+	return p
+}
+
+// configDir — каталог настроек приложения.
+func configDir() string {
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "nddtc2")
+}
+
+// policyStore загружает политику из файла и перечитывает её при изменении.
+type policyStore struct {
+	path    string
+	modTime time.Time
+	policy  *commandPolicy
+}
+
+type policyCheckMsg struct{}
+
+func newPolicyStore() *policyStore {
+	return &policyStore{path: filepath.Join(configDir(), "policy.json"), policy: defaultPolicy()}
+}
+
+// reload перечитывает файл, если он изменился. Возвращает сообщение для
+// терминала или пустую строку, если ничего не произошло.
+func (s *policyStore) reload() string {
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		if !s.modTime.IsZero() {
+			s.modTime = time.Time{}
+			s.policy = defaultPolicy()
+			return "Policy file removed, using built-in policy."
+		}
+		return ""
+	}
+	if fi.ModTime().Equal(s.modTime) {
+		return ""
+	}
+	s.modTime = fi.ModTime()
+
+	p, err := loadPolicy(s.path)
+	if err != nil {
+		// Оставляем прежнюю политику: ошибка в файле не должна всё разрешить
+		return fmt.Sprintf("Policy %s: %v (keeping previous policy)", s.path, err)
+	}
+	s.policy = p
+	return fmt.Sprintf("Policy loaded from %s (%d rules).", s.path, len(p.Rules))
+}
+
+func loadPolicy(path string) (*commandPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p commandPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	if p.Default == "" {
+		p.Default = policyBlock
+	}
+	if p.Default != policyAsk && p.Default != policyBlock {
+		return nil, fmt.Errorf("default must be %q or %q", policyAsk, policyBlock)
+	}
+	for i, r := range p.Rules {
+		switch r.Action {
+		case policyAllow, policyAsk, policyBlock:
+		default:
+			return nil, fmt.Errorf("rule %d: unknown action %q", i+1, r.Action)
+		}
+		if _, err := parseTimeout(r.Timeout); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		for _, pat := range append(append([]string{}, r.Args...), r.AnyArg...) {
+			if re, ok := strings.CutPrefix(pat, "re:"); ok {
+				if _, err := regexp.Compile(re); err != nil {
+					return nil, fmt.Errorf("rule %d: %w", i+1, err)
+				}
+			}
+		}
+	}
+	return &p, nil
+}
+
+// watchPolicy периодически проверяет файл политики.
+func watchPolicy() tea.Cmd {
+	return tea.Tick(2*time.Second, func(time.Time) tea.Msg { return policyCheckMsg{} })
+}
+
+func parseTimeout(s string) (time.Duration, error) {
+	switch s {
+	case "":
+		return defaultCommandTimeout, nil
+	case "0", "none":
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("timeout: %w", err)
+	}
+	return d, nil
+}
+
+// matchArg проверяет аргумент по шаблону "re:<regexp>" или glob.
+func matchArg(pattern, arg string) bool {
+	if re, ok := strings.CutPrefix(pattern, "re:"); ok {
+		matched, err := regexp.MatchString(re, arg)
+		return err == nil && matched
+	}
+	ok, _ := filepath.Match(strings.TrimPrefix(pattern, "glob:"), arg)
+	return ok
+}
+
+func matchAny(patterns []string, arg string) bool {
+	for _, p := range patterns {
+		if matchArg(p, arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDir проверяет рабочий каталог: "~" раскрывается, "/**" означает каталог и всё внутри.
+func matchDir(pattern, dir string) bool {
+	if strings.HasPrefix(pattern, "~") {
+		pattern = os.Getenv("HOME") + pattern[1:]
+	}
+	if base, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return dir == base || strings.HasPrefix(dir, base+"/")
+	}
+	ok, _ := filepath.Match(pattern, dir)
+	return ok
+}
+
+func (r policyRule) matches(name string, args []string, dir string) bool {
+	if ok, _ := filepath.Match(r.Command, name); !ok {
+		return false
+	}
+	if len(r.Dirs) > 0 {
+		inDir := false
+		for _, d := range r.Dirs {
+			if matchDir(d, dir) {
+				inDir = true
+				break
+			}
+		}
+		if !inDir {
+			return false
+		}
+	}
+	if len(r.Args) > 0 {
+		for _, a := range args {
+			if !matchAny(r.Args, a) {
+				return false
+			}
+		}
+	}
+	if len(r.AnyArg) > 0 {
+		found := false
+		for _, a := range args {
+			if matchAny(r.AnyArg, a) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluate решает судьбу команды name с аргументами args в каталоге dir.
+func (p *commandPolicy) evaluate(name string, args []string, dir string) policyDecision {
+	for i, r := range p.Rules {
+		if !r.matches(name, args, dir) {
+			continue
+		}
+		timeout, _ := parseTimeout(r.Timeout)
+		return policyDecision{
+			Action:  r.Action,
+			Timeout: timeout,
+			Reason:  fmt.Sprintf("rule %d (%s)", i+1, r.Command),
+			rule:    &p.Rules[i],
+		}
+	}
+	return policyDecision{Action: p.Default, Timeout: defaultCommandTimeout, Reason: "default"}
+}
+
+// allowsWrite сообщает, разрешает ли правило запись в файл target через
+// > или >>: только внутри каталогов правила. Без этой проверки любая
+// разрешённая команда перезаписала бы файл где угодно (echo x > ~/.bashrc).
+func (d policyDecision) allowsWrite(target string) bool {
+	if d.rule == nil || len(d.rule.Dirs) == 0 {
+		return false
+	}
+	// Пишется туда, куда ведут ссылки, а не туда, где лежит имя
+	target = filepath.Clean(target)
+	if real, err := filepath.EvalSymlinks(target); err == nil {
+		target = real
+	} else if dir, err := filepath.EvalSymlinks(filepath.Dir(target)); err == nil {
+		target = filepath.Join(dir, filepath.Base(target))
+	}
+	for _, pattern := range d.rule.Dirs {
+		if matchDir(pattern, filepath.Dir(target)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCommand проверяет каждую стадию конвейера по политике, пишет решения
+// в терминал и запускает его, спрашивает подтверждение или блокирует.
+// Побеждает самое строгое решение; таймаут — наименьший из стадий.
+func (m *model) checkCommand(p *pipeline, workingDir string) tea.Cmd {
+	if m.running != nil && !p.Background {
+		m.termOutput.add("A command is already running (ctrl+c to cancel).")
+		return nil
+	}
+	if note := m.policy.reload(); note != "" {
+		m.termOutput.add(note)
+	}
+	if len(p.Stages) == 0 {
+		return nil
+	}
+
+	action := policyAllow
+	var timeout time.Duration
+	var blocked, asked []string
+	for i, st := range p.Stages {
+		name, args := st.Args[0], st.Args[1:]
+		d := m.policy.policy.evaluate(name, args, workingDir)
+		if st.Stdout != "" && d.Action == policyAllow && !d.allowsWrite(resolvePath(workingDir, st.Stdout)) {
+			d.Action = policyAsk
+			d.Reason += ", writes to " + st.Stdout + " outside the rule's dirs"
+		}
+		m.termOutput.add(fmt.Sprintf("policy: %s %s — %s", d.Action, name, d.Reason))
+		switch d.Action {
+		case policyBlock:
+			action = policyBlock
+			blocked = append(blocked, name)
+		case policyAsk:
+			if action != policyBlock {
+				action = policyAsk
+			}
+			asked = append(asked, name)
+		}
+		if i == 0 || (d.Timeout > 0 && (timeout == 0 || d.Timeout < timeout)) {
+			timeout = d.Timeout
+		}
+	}
+
+	switch action {
+	case policyAllow:
+		return m.startCommand(p, workingDir, timeout)
+	case policyAsk:
+		m.confirm = &confirmDialog{
+			prompt: "Run " + p.Source + "?",
+			onYes: func(m *model) tea.Cmd {
+				m.termOutput.add("policy: confirmed " + strings.Join(asked, ", "))
+				return m.startCommand(p, workingDir, timeout)
+			},
+		}
+		return nil
+	}
+	m.termOutput.add("command not allowed: " + strings.Join(blocked, ", "))
+	return nil
+}
+
+// startCommand запускает конвейер на переднем плане терминала или, с &,
+// фоновым заданием без таймаута.
+func (m *model) startCommand(p *pipeline, workingDir string, timeout time.Duration) tea.Cmd {
+	if p.Background {
+		return m.startJob(p, workingDir)
+	}
+	run, cmd := runCommandAsync(p, workingDir, timeout)
+	m.running = run
+	return cmd
+}
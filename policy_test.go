@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAllowsWrite(t *testing.T) {
+	dir := t.TempDir()
+	ok := filepath.Join(dir, "ok")
+	if err := os.Mkdir(ok, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(dir, filepath.Join(ok, "up")); err != nil {
+		t.Fatal(err)
+	}
+	p := &commandPolicy{Default: policyBlock, Rules: []policyRule{
+		{Command: "echo", Action: policyAllow, Dirs: []string{ok + "/**"}},
+		{Command: "cat", Action: policyAllow},
+	}}
+
+	echo := p.evaluate("echo", nil, ok)
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{filepath.Join(ok, "a.txt"), true},
+		{filepath.Join(ok, "sub", "b.txt"), true},
+		{ok + "/../x", false},
+		{filepath.Join(ok, "up", "x"), false},
+		{filepath.Join(dir, "x"), false},
+	}
+	for _, tt := range tests {
+		if got := echo.allowsWrite(tt.target); got != tt.want {
+			t.Errorf("allowsWrite(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+	// Правило без каталогов не разрешает запись никуда
+	if p.evaluate("cat", nil, ok).allowsWrite(filepath.Join(ok, "a.txt")) {
+		t.Error("a rule without dirs must not allow redirects")
+	}
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Поля окна свойств.
+const (
+	propGrid = iota
+	propOctal
+	propOwner
+	propGroup
+	propMtime
+	propRecursive
+	propFileMask
+	propDirMask
+	propFieldCount
+)
+
+var propLabels = [propFieldCount]string{"Mode", "Octal", "Owner", "Group", "Modified", "Recursive", "File mask", "Dir mask"}
+
+const (
+	mtimeLayout = "2006-01-02 15:04:05"
+	passwdFile  = "/etc/passwd"
+	groupFile   = "/etc/group"
+)
+
+// propsDialog — окно прав, владельца и времени изменения для выделения.
+// Применяются только изменённые значения; начальные берутся у первого
+// элемента.
+type propsDialog struct {
+	dir       string
+	names     []string
+	field     int
+	row, col  int // курсор в сетке rwx: строки user, group, other, special
+	recursive bool
+	inputs    [propFieldCount]textinput.Model
+	initial   [propFieldCount]string
+	err       string
+}
+
+// unixMode переводит os.FileMode в 12 бит прав unix.
+func unixMode(fm os.FileMode) uint32 {
+	bits := uint32(fm.Perm())
+	if fm&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if fm&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if fm&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return bits
+}
+
+func goMode(bits uint32) os.FileMode {
+	fm := os.FileMode(bits & 0777)
+	if bits&04000 != 0 {
+		fm |= os.ModeSetuid
+	}
+	if bits&02000 != 0 {
+		fm |= os.ModeSetgid
+	}
+	if bits&01000 != 0 {
+		fm |= os.ModeSticky
+	}
+	return fm
+}
+
+// gridBit — бит права для клетки сетки.
+func gridBit(row, col int) uint32 {
+	if row == 3 {
+		return 04000 >> col
+	}
+	return 1 << (8 - (row*3 + col))
+}
+
+func parseOctalMode(s string) (uint32, error) {
+	v, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || len(s) < 3 || len(s) > 4 || v > 07777 {
+		return 0, fmt.Errorf("%q is not an octal mode like 0755", s)
+	}
+	return uint32(v), nil
+}
+
+// readAccounts читает имена и id из файла формата passwd или group.
+func readAccounts(path string) (map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	accounts := make(map[string]int)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Split(sc.Text(), ":")
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if id, err := strconv.Atoi(fields[2]); err == nil {
+			accounts[fields[0]] = id
+		}
+	}
+	return accounts, sc.Err()
+}
+
+// resolveAccount принимает имя из файла учётных записей или числовой id.
+func resolveAccount(path, value string) (int, error) {
+	if id, err := strconv.Atoi(value); err == nil && id >= 0 {
+		return id, nil
+	}
+	accounts, err := readAccounts(path)
+	if err != nil {
+		return 0, err
+	}
+	id, ok := accounts[value]
+	if !ok {
+		return 0, fmt.Errorf("%q not found in %s", value, path)
+	}
+	return id, nil
+}
+
+// accountName — имя для id или сам id, если имени нет.
+func accountName(path string, id int) string {
+	accounts, _ := readAccounts(path)
+	for name, v := range accounts {
+		if v == id {
+			return name
+		}
+	}
+	return strconv.Itoa(id)
+}
+
+func (m *model) openProps() tea.Cmd {
+	if m.refuseInArchive("Properties") {
+		return nil
+	}
+	names := m.activeSelection()
+	if len(names) == 0 {
+		items, cursor := m.activeItems(), m.leftCursor
+		if m.activePanel == 1 {
+			cursor = m.rightCursor
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		names = []string{items[cursor]}
+	}
+	dir := m.activeDir()
+	info, err := os.Stat(filepath.Join(dir, names[0]))
+	if err != nil {
+		m.termOutput.add("Properties: " + err.Error())
+		return nil
+	}
+
+	d := &propsDialog{dir: dir, names: names}
+	for i := range d.inputs {
+		ti := textinput.New()
+		ti.Prompt = ""
+		ti.CharLimit = 64
+		ti.Width = 30
+		d.inputs[i] = ti
+	}
+	d.inputs[propOctal].SetValue(fmt.Sprintf("%04o", unixMode(info.Mode())))
+	if uid, gid, ok := fileOwner(info); ok {
+		d.inputs[propOwner].SetValue(accountName(passwdFile, uid))
+		d.inputs[propGroup].SetValue(accountName(groupFile, gid))
+	}
+	d.inputs[propMtime].SetValue(info.ModTime().Format(mtimeLayout))
+	d.inputs[propFileMask].SetValue("7777")
+	d.inputs[propDirMask].SetValue("7777")
+	for i := range d.inputs {
+		d.initial[i] = d.inputs[i].Value()
+	}
+	m.props = d
+	return nil
+}
+
+// changed — значение поля отличается от начального.
+func (d *propsDialog) changed(field int) bool {
+	return d.inputs[field].Value() != d.initial[field]
+}
+
+// focus переходит на соседнее поле; маски доступны только при рекурсии.
+func (d *propsDialog) focus(step int) tea.Cmd {
+	for {
+		d.field = (d.field + step + propFieldCount) % propFieldCount
+		if d.recursive || (d.field != propFileMask && d.field != propDirMask) {
+			break
+		}
+	}
+	for i := range d.inputs {
+		d.inputs[i].Blur()
+	}
+	if d.field == propGrid || d.field == propRecursive {
+		return nil
+	}
+	return d.inputs[d.field].Focus()
+}
+
+func (m model) updateProps(msg tea.KeyMsg) (model, tea.Cmd) {
+	d := m.props
+	switch msg.String() {
+	case "esc":
+		m.props = nil
+		return m, nil
+	case "tab":
+		return m, d.focus(1)
+	case "shift+tab":
+		return m, d.focus(-1)
+	case "enter":
+		cmd, err := m.submitProps()
+		if err != nil {
+			d.err = err.Error()
+		}
+		return m, cmd
+	}
+	d.err = ""
+
+	switch d.field {
+	case propGrid:
+		switch msg.String() {
+		case "up", "k":
+			d.row = max(0, d.row-1)
+		case "down", "j":
+			d.row = min(3, d.row+1)
+		case "left", "h":
+			d.col = max(0, d.col-1)
+		case "right", "l":
+			d.col = min(2, d.col+1)
+		case " ", "x":
+			mode, err := parseOctalMode(d.inputs[propOctal].Value())
+			if err != nil {
+				d.err = err.Error()
+				break
+			}
+			d.inputs[propOctal].SetValue(fmt.Sprintf("%04o", mode^gridBit(d.row, d.col)))
+		}
+		return m, nil
+	case propRecursive:
+		if msg.String() == " " || msg.String() == "x" {
+			d.recursive = !d.recursive
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	d.inputs[d.field], cmd = d.inputs[d.field].Update(msg)
+	return m, cmd
+}
+
+// propsChange — проверенные изменения для задания.
+type propsChange struct {
+	chmod     bool
+	mode      uint32
+	fileMask  uint32
+	dirMask   uint32
+	uid, gid  int // -1 — не менять
+	mtime     time.Time
+	recursive bool
+}
+
+func (m *model) submitProps() (tea.Cmd, error) {
+	d := m.props
+	c := propsChange{uid: -1, gid: -1, fileMask: 07777, dirMask: 07777, recursive: d.recursive}
+	var err error
+	if d.changed(propOctal) {
+		if c.mode, err = parseOctalMode(d.inputs[propOctal].Value()); err != nil {
+			return nil, err
+		}
+		c.chmod = true
+	}
+	if d.changed(propOwner) {
+		if c.uid, err = resolveAccount(passwdFile, d.inputs[propOwner].Value()); err != nil {
+			return nil, fmt.Errorf("owner: %w", err)
+		}
+	}
+	if d.changed(propGroup) {
+		if c.gid, err = resolveAccount(groupFile, d.inputs[propGroup].Value()); err != nil {
+			return nil, fmt.Errorf("group: %w", err)
+		}
+	}
+	if d.changed(propMtime) {
+		if c.mtime, err = time.ParseInLocation(mtimeLayout, d.inputs[propMtime].Value(), time.Local); err != nil {
+			return nil, fmt.Errorf("modified: use %s", mtimeLayout)
+		}
+	}
+	if c.recursive && c.chmod {
+		if c.fileMask, err = parseOctalMode(d.inputs[propFileMask].Value()); err != nil {
+			return nil, fmt.Errorf("file mask: %w", err)
+		}
+		if c.dirMask, err = parseOctalMode(d.inputs[propDirMask].Value()); err != nil {
+			return nil, fmt.Errorf("dir mask: %w", err)
+		}
+	}
+
+	m.props = nil
+	if !c.chmod && c.uid < 0 && c.gid < 0 && c.mtime.IsZero() {
+		m.termOutput.add("Properties: nothing changed.")
+		return nil, nil
+	}
+	title := "properties " + strings.Join(d.names, " ")
+	if len(d.names) > 3 {
+		title = fmt.Sprintf("properties of %d entries in %s", len(d.names), d.dir)
+	}
+	dir, names := d.dir, d.names
+	return m.startTask(title, func(ctx context.Context, out taskOutput) error {
+		return c.run(ctx, dir, names, out)
+	}), nil
+}
+
+// apply меняет владельца, права и время одного элемента. Владелец меняется
+// первым: chown сбрасывает setuid и setgid.
+func (c propsChange) apply(path string, info fs.FileInfo) error {
+	var errs []error
+	if c.uid >= 0 || c.gid >= 0 {
+		errs = append(errs, os.Chown(path, c.uid, c.gid))
+	}
+	if c.chmod {
+		mode := c.mode
+		if c.recursive {
+			if info.IsDir() {
+				mode &= c.dirMask
+			} else {
+				mode &= c.fileMask
+			}
+		}
+		errs = append(errs, os.Chmod(path, goMode(mode)))
+	}
+	if !c.mtime.IsZero() {
+		errs = append(errs, os.Chtimes(path, time.Time{}, c.mtime))
+	}
+	return errors.Join(errs...)
+}
+
+// dirMode — права, которые получит каталог.
+func (c propsChange) dirMode() uint32 {
+	if c.recursive {
+		return c.mode & c.dirMask
+	}
+	return c.mode
+}
+
+// run применяет изменения к names и, при рекурсии, к их содержимому.
+// Символические ссылки внутри деревьев пропускаются: chmod и chown
+// изменили бы их цели.
+func (c propsChange) run(ctx context.Context, dir string, names []string, out taskOutput) error {
+	done, failed := 0, 0
+	visit := func(path string, info fs.FileInfo) {
+		if err := c.apply(path, info); err != nil {
+			failed++
+			out(err.Error(), true)
+			return
+		}
+		done++
+	}
+
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			failed++
+			out(err.Error(), true)
+			continue
+		}
+		if !c.recursive || !info.IsDir() {
+			visit(path, info)
+			continue
+		}
+
+		// Каталог, который станет непроходимым, меняем уже после обхода
+		// его содержимого, иначе обход в него не войдёт
+		type pending struct {
+			path string
+			info fs.FileInfo
+		}
+		var deferred []pending
+		err = filepath.WalkDir(path, func(p string, entry fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				failed++
+				out(err.Error(), true)
+				return nil
+			}
+			if entry.Type()&fs.ModeSymlink != 0 {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				failed++
+				out(err.Error(), true)
+				return nil
+			}
+			if entry.IsDir() && c.chmod && c.dirMode()&0500 != 0500 {
+				deferred = append(deferred, pending{p, info})
+				return nil
+			}
+			visit(p, info)
+			return nil
+		})
+		for i := len(deferred) - 1; i >= 0; i-- {
+			visit(deferred[i].path, deferred[i].info)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	out(fmt.Sprintf("%d entries updated, %d failed", done, failed), false)
+	if failed > 0 {
+		return fmt.Errorf("%d failed", failed)
+	}
+	return nil
+}
+
+func (m model) renderProps() string {
+	d := m.props
+	popupWidth := 64
+	popupStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("171")).
+		Padding(1, 2).
+		Width(popupWidth)
+	hint := lipgloss.NewStyle().Faint(true)
+	active := lipgloss.NewStyle().Foreground(lipgloss.Color("171")).Bold(true)
+
+	what := d.names[0]
+	if len(d.names) > 1 {
+		what = fmt.Sprintf("%d entries (values of %s)", len(d.names), d.names[0])
+	}
+	lines := []string{lipgloss.NewStyle().Bold(true).Render("Properties of " + what), ""}
+
+	marker := func(field int) string {
+		if field == d.field {
+			return active.Render("● ")
+		}
+		return "  "
+	}
+	mode, modeErr := parseOctalMode(d.inputs[propOctal].Value())
+	lines = append(lines, marker(propGrid)+fmt.Sprintf("%-11s %-7s%-7s%-7s", propLabels[propGrid], "read", "write", "exec"))
+	rowLabels := []string{"user", "group", "other", ""}
+	for row := 0; row < 4; row++ {
+		if row == 3 {
+			lines = append(lines, fmt.Sprintf("  %-11s %-7s%-7s%-7s", "", "setuid", "setgid", "sticky"))
+		}
+		line := fmt.Sprintf("    %-9s ", rowLabels[row])
+		for col := 0; col < 3; col++ {
+			cell := "[ ]"
+			if modeErr == nil && mode&gridBit(row, col) != 0 {
+				cell = "[x]"
+			}
+			if d.field == propGrid && d.row == row && d.col == col {
+				cell = active.Reverse(true).Render(cell)
+			}
+			line += cell + "    "
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "")
+
+	for field := propOctal; field < propFieldCount; field++ {
+		if !d.recursive && (field == propFileMask || field == propDirMask) {
+			continue
+		}
+		value := d.inputs[field].View()
+		if field == propRecursive {
+			value = "[ ]"
+			if d.recursive {
+				value = "[x]"
+			}
+		}
+		lines = append(lines, marker(field)+fmt.Sprintf("%-11s %s", propLabels[field], value))
+	}
+	if d.recursive {
+		lines = append(lines, hint.Render("Files get mode & file mask, directories mode & dir mask"))
+	}
+	if d.err != "" {
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(d.err))
+	}
+	lines = append(lines, "", hint.Render("Only changed values are applied"),
+		hint.Render("Tab field • ←↑↓→ Space grid • Enter apply • Esc cancel"))
+
+	popup := popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	x := (m.width - popupWidth) / 2
+	y := (m.height - lipgloss.Height(popup)) / 2
+	if y < 0 {
+		y = 0
+	}
+	return lipgloss.NewStyle().MarginLeft(x).MarginTop(y).Render(popup)
+}
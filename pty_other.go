@@ -0,0 +1,27 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+var errNoPTY = errors.New("interactive shell is only supported on Linux")
+
+func startPTY(cmd *exec.Cmd, rows, cols int) (*os.File, error) {
+	return nil, errNoPTY
+}
+
+func resizePTY(master *os.File, rows, cols int) error {
+	return errNoPTY
+}
+
+func ptyForegroundIsShell(master *os.File, pid int) bool {
+	return false
+}
+
+func processCwd(pid int) (string, error) {
+	return "", errNoPTY
+}
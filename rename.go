@@ -0,0 +1,404 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// renamePair — переименование внутри одного каталога (только имена).
+type renamePair struct {
+	Old, New string
+}
+
+// bulkEditMsg приходит, когда редактор с именами закрыт.
+type bulkEditMsg struct {
+	dir   string
+	tmp   string
+	names []string
+	err   error
+}
+
+// activeSelection — отсортированные выделенные имена активной панели.
+func (m model) activeSelection() []string {
+	selected := m.selectedLeft
+	if m.activePanel == 1 {
+		selected = m.selectedRight
+	}
+	var names []string
+	for name := range selected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// activeItems — элементы активной панели.
+func (m model) activeItems() []string {
+	if m.activePanel == 1 {
+		return m.rightItems
+	}
+	return m.leftItems
+}
+
+// editorCommand — $VISUAL или $EDITOR (с аргументами), иначе vi.
+func editorCommand(file string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	words := strings.Fields(editor)
+	if len(words) == 0 {
+		words = []string{"vi"}
+	}
+	return exec.Command(words[0], append(words[1:], file)...)
+}
+
+// startBulkRename записывает имена (выделенные или весь каталог) во
+// временный файл и открывает его в редакторе, приостановив интерфейс.
+func (m *model) startBulkRename() tea.Cmd {
+	if m.refuseInArchive("Bulk rename") {
+		return nil
+	}
+	dir := m.activeDir()
+	names := m.activeSelection()
+	if len(names) == 0 {
+		names = append(names, m.activeItems()...)
+	}
+	if len(names) == 0 {
+		m.termOutput.add("Bulk rename: nothing to rename.")
+		return nil
+	}
+
+	f, err := os.CreateTemp("", "nddtc2-rename-*.txt")
+	if err != nil {
+		m.termOutput.add("Bulk rename: " + err.Error())
+		return nil
+	}
+	_, err = f.WriteString(strings.Join(names, "\n") + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		m.termOutput.add("Bulk rename: " + err.Error())
+		return nil
+	}
+
+	tmp := f.Name()
+	return tea.ExecProcess(editorCommand(tmp), func(err error) tea.Msg {
+		return bulkEditMsg{dir: dir, tmp: tmp, names: names, err: err}
+	})
+}
+
+// finishBulkEdit сравнивает отредактированный файл с исходными именами,
+// проверяет план и спрашивает подтверждение.
+func (m *model) finishBulkEdit(msg bulkEditMsg) tea.Cmd {
+	defer os.Remove(msg.tmp)
+	if msg.err != nil {
+		m.termOutput.add("Bulk rename: editor: " + msg.err.Error())
+		return nil
+	}
+	data, err := os.ReadFile(msg.tmp)
+	if err != nil {
+		m.termOutput.add("Bulk rename: " + err.Error())
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) != len(msg.names) {
+		m.termOutput.add(fmt.Sprintf("Bulk rename: expected %d lines, got %d; lines must not be added or removed. Nothing renamed.", len(msg.names), len(lines)))
+		return nil
+	}
+
+	var pairs []renamePair
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if line != msg.names[i] {
+			pairs = append(pairs, renamePair{Old: msg.names[i], New: line})
+		}
+	}
+	if len(pairs) == 0 {
+		m.termOutput.add("Bulk rename: nothing changed.")
+		return nil
+	}
+	if problems := checkRenamePlan(msg.dir, pairs); len(problems) > 0 {
+		m.termOutput.add("Bulk rename: conflicts found, nothing renamed:")
+		m.termOutput.add(problems...)
+		return nil
+	}
+	m.confirmRenames("Bulk rename", msg.dir, pairs)
+	return nil
+}
+
+// confirmRenames печатает план и применяет его после подтверждения.
+func (m *model) confirmRenames(title, dir string, pairs []renamePair) {
+	const preview = 8
+	m.termOutput.add(fmt.Sprintf("%s plan for %s:", title, dir))
+	var lines []string
+	for i, p := range pairs {
+		line := fmt.Sprintf("  %s → %s", p.Old, p.New)
+		m.termOutput.add(line)
+		if i < preview {
+			lines = append(lines, line)
+		}
+	}
+	if len(pairs) > preview {
+		lines = append(lines, fmt.Sprintf("  … and %d more", len(pairs)-preview))
+	}
+
+	m.confirm = &confirmDialog{
+		prompt: fmt.Sprintf("Rename %d entries?\n\n%s", len(pairs), strings.Join(lines, "\n")),
+		onYes: func(m *model) tea.Cmd {
+			m.applyRenamePlan(title, dir, pairs)
+			return nil
+		},
+	}
+}
+
+// applyRenamePlan выполняет план, запоминает его для отмены и обновляет панели.
+func (m *model) applyRenamePlan(title, dir string, pairs []renamePair) []renamePair {
+	done, err := applyRenames(dir, pairs)
+	if len(done) > 0 {
+		m.lastRename = &renameBatch{dir: dir, pairs: done}
+	}
+	if err != nil {
+		m.termOutput.add(fmt.Sprintf("%s: %v (%d of %d renamed)", title, err, len(done), len(pairs)))
+	} else {
+		m.termOutput.add(fmt.Sprintf("%s: renamed %d entries.", title, len(done)))
+	}
+	if m.activeDir() == dir {
+		if m.activePanel == 0 {
+			m.selectedLeft = make(map[string]bool)
+		} else {
+			m.selectedRight = make(map[string]bool)
+		}
+	}
+	m.refreshPanelsAfterChange(dir)
+	m.adjustScroll()
+	return done
+}
+
+// validName проверяет имя файла без обращения к диску.
+func validName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("empty name")
+	case name == "." || name == "..":
+		return fmt.Errorf("%q is reserved", name)
+	case strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator):
+		return fmt.Errorf("%q contains a path separator", name)
+	case strings.ContainsRune(name, 0):
+		return fmt.Errorf("%q contains a NUL byte", name)
+	case !utf8.ValidString(name):
+		return fmt.Errorf("%q is not valid UTF-8", name)
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return fmt.Errorf("%q contains control characters", name)
+	}
+	return nil
+}
+
+// renameProblems ищет проблемы до того, как что-либо переименовано:
+// неверные имена, совпадающие цели и занятые имена вне плана.
+// Ключ — старое имя.
+func renameProblems(dir string, pairs []renamePair) map[string]string {
+	problems := make(map[string]string)
+	olds := make(map[string]bool)
+	for _, p := range pairs {
+		olds[p.Old] = true
+	}
+	targets := make(map[string]string)
+	for _, p := range pairs {
+		if err := validName(p.New); err != nil {
+			problems[p.Old] = err.Error()
+			continue
+		}
+		oldInfo, err := os.Lstat(filepath.Join(dir, p.Old))
+		if err != nil {
+			problems[p.Old] = "no longer exists"
+			continue
+		}
+		if other, ok := targets[p.New]; ok {
+			problems[other] = "same new name as " + p.Old
+			problems[p.Old] = "same new name as " + other
+			continue
+		}
+		targets[p.New] = p.Old
+		if olds[p.New] {
+			// Имя освобождается внутри этого же плана
+			continue
+		}
+		if info, err := os.Lstat(filepath.Join(dir, p.New)); err == nil && !os.SameFile(info, oldInfo) {
+			// SameFile пропускает смену регистра на нечувствительных к нему ФС
+			problems[p.Old] = p.New + " already exists"
+		}
+	}
+	return problems
+}
+
+// checkRenamePlan — проблемы плана строками для терминала, в порядке плана.
+func checkRenamePlan(dir string, pairs []renamePair) []string {
+	problems := renameProblems(dir, pairs)
+	var lines []string
+	for _, p := range pairs {
+		if msg, ok := problems[p.Old]; ok {
+			lines = append(lines, fmt.Sprintf("  %s → %s: %s", p.Old, p.New, msg))
+		}
+	}
+	return lines
+}
+
+// applyRenames переименовывает в два прохода через временные имена, так что
+// обмены и циклы (a→b, b→a) безопасны. При ошибке на первом проходе всё
+// возвращается как было; возвращаются выполненные переименования.
+func applyRenames(dir string, pairs []renamePair) ([]renamePair, error) {
+	tmp := func(i int) string {
+		return filepath.Join(dir, fmt.Sprintf(".nddtc2-rename-%d-%d", os.Getpid(), i))
+	}
+
+	for i, p := range pairs {
+		if err := os.Rename(filepath.Join(dir, p.Old), tmp(i)); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				os.Rename(tmp(j), filepath.Join(dir, pairs[j].Old))
+			}
+			return nil, err
+		}
+	}
+
+	var done []renamePair
+	for i, p := range pairs {
+		if err := os.Rename(tmp(i), filepath.Join(dir, p.New)); err != nil {
+			// Оставшиеся возвращаем под старые имена, если они свободны
+			var stuck []string
+			for j := i; j < len(pairs); j++ {
+				old := filepath.Join(dir, pairs[j].Old)
+				if _, statErr := os.Lstat(old); statErr == nil || os.Rename(tmp(j), old) != nil {
+					stuck = append(stuck, filepath.Base(tmp(j))+" ("+pairs[j].Old+")")
+				}
+			}
+			if len(stuck) > 0 {
+				err = fmt.Errorf("%w; left as %s", err, strings.Join(stuck, ", "))
+			}
+			return done, err
+		}
+		done = append(done, p)
+	}
+	return done, nil
+}
+
+// openRenamePopup открывает окно переименования элемента под курсором с
+// текущим именем в поле ввода и выделенной основой (без расширения).
+func (m *model) openRenamePopup() tea.Cmd {
+	if m.refuseInArchive("Rename") {
+		return nil
+	}
+	items, cursor := m.activeItems(), m.leftCursor
+	if m.activePanel == 1 {
+		cursor = m.rightCursor
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	name := items[cursor]
+	m.renaming = true
+	m.renamePanel = m.activePanel
+	m.renameOldPath = filepath.Join(m.activeDir(), name)
+	m.renameErr = ""
+
+	m.renameInput = textinput.New()
+	m.renameInput.CharLimit = 255
+	m.renameInput.Width = 40
+	m.renameInput.SetValue(filepath.Base(name))
+	m.renameStemSelected = true
+	stem, _ := m.renameStem()
+	m.renameInput.SetCursor(len([]rune(stem)))
+	return m.renameInput.Focus()
+}
+
+// renameStem делит текущее значение на выделяемую основу и расширение.
+// У каталогов выделяется всё имя.
+func (m model) renameStem() (string, string) {
+	value := m.renameInput.Value()
+	if fi, err := os.Stat(m.renameOldPath); err == nil && fi.IsDir() {
+		return value, ""
+	}
+	return splitExt(value)
+}
+
+func (m model) updateRenamePopup(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.renaming = false
+		return m, nil
+	case "enter":
+		cmd := m.submitRename()
+		return m, cmd
+	}
+	m.renameErr = ""
+
+	if m.renameStemSelected {
+		m.renameStemSelected = false
+		stem, ext := m.renameStem()
+		switch {
+		case msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace:
+			// Ввод заменяет выделенную основу
+			typed := string(msg.Runes)
+			if msg.Type == tea.KeySpace {
+				typed = " "
+			}
+			m.renameInput.SetValue(typed + ext)
+			m.renameInput.SetCursor(len([]rune(typed)))
+			return m, nil
+		case msg.Type == tea.KeyBackspace || msg.Type == tea.KeyDelete:
+			m.renameInput.SetValue(ext)
+			m.renameInput.SetCursor(0)
+			return m, nil
+		case msg.Type == tea.KeyLeft:
+			m.renameInput.SetCursor(0)
+			return m, nil
+		case msg.Type == tea.KeyRight:
+			m.renameInput.SetCursor(len([]rune(stem)))
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.renameInput, cmd = m.renameInput.Update(msg)
+	return m, cmd
+}
+
+// submitRename проверяет новое имя и переименовывает; при ошибке окно
+// остаётся открытым с сообщением.
+func (m *model) submitRename() tea.Cmd {
+	dir := filepath.Dir(m.renameOldPath)
+	oldName := filepath.Base(m.renameOldPath)
+	newName := m.renameInput.Value()
+	if newName == oldName {
+		m.renaming = false
+		return nil
+	}
+	pairs := []renamePair{{Old: oldName, New: newName}}
+	if problem, ok := renameProblems(dir, pairs)[oldName]; ok {
+		m.renameErr = problem
+		return nil
+	}
+
+	m.renaming, m.renameErr = false, ""
+	if done := m.applyRenamePlan("Rename", dir, pairs); len(done) == 1 {
+		// Курсор остаётся на переименованном элементе
+		for i, name := range m.activeItems() {
+			if name == newName {
+				m.setCursor(i)
+				break
+			}
+		}
+	}
+	return nil
+}
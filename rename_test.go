@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidName(t *testing.T) {
+	for _, name := range []string{"a.txt", ".hidden", "имя файла", "a b"} {
+		if err := validName(name); err != nil {
+			t.Errorf("validName(%q): %v", name, err)
+		}
+	}
+	for _, name := range []string{"", ".", "..", "a/b", "a\x00b", "a\nb", "\xff"} {
+		if err := validName(name); err == nil {
+			t.Errorf("validName(%q): expected an error", name)
+		}
+	}
+}
+
+func TestRenameProblems(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c", "d", "taken"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		pairs []renamePair
+		want  map[string]string
+	}{
+		{"swap", []renamePair{{"a", "b"}, {"b", "a"}}, map[string]string{}},
+		{"chain", []renamePair{{"a", "b"}, {"b", "c"}, {"c", "x"}}, map[string]string{}},
+		{"taken", []renamePair{{"a", "taken"}}, map[string]string{"a": "taken already exists"}},
+		{"collision", []renamePair{{"a", "x"}, {"b", "x"}}, map[string]string{
+			"a": "same new name as b",
+			"b": "same new name as a",
+		}},
+		{"missing", []renamePair{{"gone", "x"}}, map[string]string{"gone": "no longer exists"}},
+		{"invalid", []renamePair{{"d", "a/b"}}, map[string]string{"d": `"a/b" contains a path separator`}},
+	}
+	for _, tt := range tests {
+		if got := renameProblems(dir, tt.pairs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: renameProblems = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRenamesSwap(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a"), []byte("A"), 0644)
+	os.WriteFile(filepath.Join(dir, "b"), []byte("B"), 0644)
+
+	done, err := applyRenames(dir, []renamePair{{"a", "b"}, {"b", "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 2 {
+		t.Errorf("applyRenames reported %d renames, want 2", len(done))
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a")); string(data) != "B" {
+		t.Errorf("a = %q after swap, want B", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "b")); string(data) != "A" {
+		t.Errorf("b = %q after swap, want A", data)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var (
+	matchStyle        = lipgloss.NewStyle().Background(lipgloss.Color("58"))
+	currentMatchStyle = lipgloss.NewStyle().Background(lipgloss.Color("214")).Foreground(lipgloss.Color("0"))
+)
+
+// scrollback — кольцевой буфер вывода терминала. Строки нумеруются
+// сквозным номером seq: самая старая из хранимых — total-count.
+type scrollback struct {
+	lines []string
+	start int // индекс самой старой строки в lines
+	count int
+	total int // сколько строк добавлено за всё время
+
+	offset int // на сколько строк вид прокручен вверх от конца
+	search *scrollSearch
+}
+
+// scrollSearch — поиск по буферу (ctrl+s в терминале).
+type scrollSearch struct {
+	query   string
+	matches []int // seq строк с совпадениями, по возрастанию
+	current int   // индекс в matches, -1 — совпадений нет
+}
+
+func newScrollback(size int) *scrollback {
+	return &scrollback{lines: make([]string, size)}
+}
+
+// add дописывает строки; многострочный текст разбивается, чтобы одна
+// запись буфера была одной строкой на экране.
+func (s *scrollback) add(lines ...string) {
+	for _, text := range lines {
+		for _, line := range strings.Split(text, "\n") {
+			if s.count < len(s.lines) {
+				s.lines[(s.start+s.count)%len(s.lines)] = line
+				s.count++
+			} else {
+				s.lines[s.start] = line
+				s.start = (s.start + 1) % len(s.lines)
+			}
+			s.total++
+			// Прокрученный вид остаётся на месте, пока приходит новый вывод
+			if s.offset > 0 {
+				s.offset = min(s.offset+1, s.count-1)
+			}
+		}
+	}
+}
+
+func (s *scrollback) first() int { return s.total - s.count }
+
+// at возвращает строку по сквозному номеру.
+func (s *scrollback) at(seq int) string {
+	return s.lines[(s.start+seq-s.first())%len(s.lines)]
+}
+
+// view — не более n строк, заканчивающихся с учётом прокрутки.
+func (s *scrollback) view(n int) []string {
+	end := s.total - s.offset
+	begin := max(end-n, s.first())
+	out := make([]string, 0, end-begin)
+	for seq := begin; seq < end; seq++ {
+		out = append(out, s.at(seq))
+	}
+	return out
+}
+
+// scroll прокручивает вид; положительный delta — вверх, к старым строкам.
+func (s *scrollback) scroll(delta int) {
+	s.offset = max(0, min(s.offset+delta, s.count-1))
+}
+
+func (s *scrollback) clear() {
+	s.start, s.count, s.offset = 0, 0, 0
+	s.search = nil
+}
+
+// save записывает весь буфер в файл без escape-последовательностей.
+func (s *scrollback) save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for seq := s.first(); seq < s.total; seq++ {
+		w.WriteString(ansi.Strip(s.at(seq)) + "\n")
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// setQuery ищет query во всём буфере и переходит к самому новому совпадению.
+func (s *scrollback) setQuery(query string) {
+	s.search.query = query
+	s.search.matches = nil
+	s.search.current = -1
+	if query == "" {
+		return
+	}
+	for seq := s.first(); seq < s.total; seq++ {
+		if strings.Contains(ansi.Strip(s.at(seq)), query) {
+			s.search.matches = append(s.search.matches, seq)
+		}
+	}
+	s.jumpMatch(len(s.search.matches) - 1)
+}
+
+// jumpMatch прокручивает вид так, чтобы совпадение i оказалось внизу.
+func (s *scrollback) jumpMatch(i int) {
+	ss := s.search
+	if i < 0 || i >= len(ss.matches) || ss.matches[i] < s.first() {
+		return
+	}
+	ss.current = i
+	s.offset = s.total - 1 - ss.matches[i]
+}
+
+// highlight подсвечивает совпадения в строке seq; цвета строки при этом теряются.
+func (s *scrollback) highlight(seq int, line string) string {
+	ss := s.search
+	if ss == nil || ss.query == "" {
+		return line
+	}
+	plain := ansi.Strip(line)
+	if !strings.Contains(plain, ss.query) {
+		return line
+	}
+	style := matchStyle
+	if ss.current >= 0 && ss.matches[ss.current] == seq {
+		style = currentMatchStyle
+	}
+	parts := strings.Split(plain, ss.query)
+	return strings.Join(parts, style.Render(ss.query))
+}
+
+// render — видимые строки вывода с подсветкой поиска.
+func (s *scrollback) render(n int) []string {
+	lines := s.view(n)
+	seq := s.total - s.offset - len(lines)
+	for i := range lines {
+		lines[i] = s.highlight(seq+i, lines[i])
+	}
+	return lines
+}
+
+// updateSearchKey обрабатывает клавиши в режиме поиска по выводу.
+func (s *scrollback) updateSearchKey(msg tea.KeyMsg) {
+	ss := s.search
+	switch msg.String() {
+	case "ctrl+s", "up":
+		s.jumpMatch(ss.current - 1)
+	case "down":
+		s.jumpMatch(ss.current + 1)
+	case "backspace":
+		if r := []rune(ss.query); len(r) > 0 {
+			s.setQuery(string(r[:len(r)-1]))
+		}
+	case "enter":
+		// Остаёмся на найденном месте
+		s.search = nil
+	case "esc", "ctrl+g":
+		s.search = nil
+		s.offset = 0
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			s.setQuery(ss.query + string(msg.Runes))
+		}
+	}
+}
+
+// searchPrompt — строка ввода в режиме поиска по выводу.
+func (s *scrollback) searchPrompt() string {
+	ss := s.search
+	status := "no matches"
+	if ss.query == "" {
+		status = "type to search"
+	} else if ss.current >= 0 {
+		status = fmt.Sprintf("%d/%d", ss.current+1, len(ss.matches))
+	}
+	return fmt.Sprintf("(scrollback-search)`%s': %s  [↑/ctrl+s older • ↓ newer • enter stay • esc back]", ss.query, status)
+}
+
+// scrollbackBuiltin — команда scrollback: save [файл] или clear.
+func (m *model) scrollbackBuiltin(args []string) tea.Cmd {
+	sub := ""
+	if len(args) > 1 {
+		sub = args[1]
+	}
+	switch sub {
+	case "save":
+		dir := m.activeDir()
+		path := filepath.Join(dir, "scrollback-"+time.Now().Format("20060102-150405")+".txt")
+		if len(args) > 2 {
+			path = resolvePath(dir, args[2])
+		}
+		if err := m.termOutput.save(path); err != nil {
+			m.termOutput.add("scrollback: " + err.Error())
+			return nil
+		}
+		m.termOutput.add(fmt.Sprintf("Saved %d lines to %s", m.termOutput.count, path))
+		m.refreshPanelsAfterChange(filepath.Dir(path))
+	case "clear":
+		m.termOutput.clear()
+	default:
+		m.termOutput.add("usage: scrollback save [file] | scrollback clear")
+	}
+	return nil
+}
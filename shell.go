@@ -0,0 +1,290 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// shellSession — пользовательский $SHELL, запущенный в псевдотерминале.
+type shellSession struct {
+	pty    *os.File
+	cmd    *exec.Cmd
+	screen *vtScreen
+	output chan []byte
+	exited chan error
+
+	syncedDir string // каталог, о котором шелл уже знает
+}
+
+type shellOutputMsg struct {
+	Data []byte
+}
+
+type shellExitMsg struct {
+	Err error
+}
+
+// startShell запускает $SHELL (или /bin/sh) в каталоге dir.
+func startShell(dir string, rows, cols int) (*shellSession, error) {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	cmd := exec.Command(shell)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+
+	pty, err := startPTY(cmd, rows, cols)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &shellSession{
+		pty:       pty,
+		cmd:       cmd,
+		screen:    newVTScreen(rows, cols),
+		output:    make(chan []byte, 64),
+		exited:    make(chan error, 1),
+		syncedDir: dir,
+	}
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := pty.Read(buf)
+			if n > 0 {
+				s.output <- append([]byte(nil), buf[:n]...)
+			}
+			if err != nil {
+				close(s.output)
+				s.exited <- cmd.Wait()
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// waitShellOutput ждёт следующую порцию вывода шелла.
+func (s *shellSession) waitShellOutput() tea.Cmd {
+	return func() tea.Msg {
+		data, ok := <-s.output
+		if !ok {
+			return shellExitMsg{Err: <-s.exited}
+		}
+		// Склеиваем всё, что уже накопилось, чтобы не перерисовывать на каждый кусок
+		for {
+			select {
+			case more, ok := <-s.output:
+				if !ok {
+					return shellOutputMsg{Data: data}
+				}
+				data = append(data, more...)
+			default:
+				return shellOutputMsg{Data: data}
+			}
+		}
+	}
+}
+
+func (s *shellSession) write(data []byte) {
+	_, _ = s.pty.Write(data)
+}
+
+func (s *shellSession) close() {
+	if s.cmd.Process != nil {
+		_ = s.cmd.Process.Kill()
+	}
+	_ = s.pty.Close()
+}
+
+// resize подгоняет экран эмулятора и окно pty под размер терминальной панели.
+func (s *shellSession) resize(rows, cols int) {
+	if rows == s.screen.rows && cols == s.screen.cols {
+		return
+	}
+	s.screen.Resize(rows, cols)
+	_ = resizePTY(s.pty, rows, cols)
+}
+
+// shellQuote заключает строку в одинарные кавычки для POSIX-шелла.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// syncDir переводит шелл в каталог dir, если он ждёт ввода.
+// Ведущий пробел не даёт команде попасть в историю bash/zsh.
+func (s *shellSession) syncDir(dir string) {
+	if dir == s.syncedDir || !ptyForegroundIsShell(s.pty, s.cmd.Process.Pid) {
+		return
+	}
+	s.write([]byte(" cd -- " + shellQuote(dir) + "\r"))
+	s.syncedDir = dir
+}
+
+// keyBytes переводит нажатие клавиши в последовательность байт для pty.
+func keyBytes(msg tea.KeyMsg, appCursor bool) []byte {
+	var out string
+	arrow := func(c byte) string {
+		if appCursor {
+			return "\x1bO" + string(c)
+		}
+		return "\x1b[" + string(c)
+	}
+	switch msg.Type {
+	case tea.KeyRunes:
+		out = string(msg.Runes)
+		if msg.Paste {
+			out = "\x1b[200~" + out + "\x1b[201~"
+		}
+	case tea.KeySpace:
+		out = " "
+	case tea.KeyEnter:
+		out = "\r"
+	case tea.KeyBackspace:
+		out = "\x7f"
+	case tea.KeyTab:
+		out = "\t"
+	case tea.KeyShiftTab:
+		out = "\x1b[Z"
+	case tea.KeyEsc:
+		out = "\x1b"
+	case tea.KeyUp:
+		out = arrow('A')
+	case tea.KeyDown:
+		out = arrow('B')
+	case tea.KeyRight:
+		out = arrow('C')
+	case tea.KeyLeft:
+		out = arrow('D')
+	case tea.KeyHome:
+		out = "\x1b[H"
+	case tea.KeyEnd:
+		out = "\x1b[F"
+	case tea.KeyPgUp:
+		out = "\x1b[5~"
+	case tea.KeyPgDown:
+		out = "\x1b[6~"
+	case tea.KeyDelete:
+		out = "\x1b[3~"
+	case tea.KeyInsert:
+		out = "\x1b[2~"
+	case tea.KeyF1:
+		out = "\x1bOP"
+	case tea.KeyF2:
+		out = "\x1bOQ"
+	case tea.KeyF3:
+		out = "\x1bOR"
+	case tea.KeyF4:
+		out = "\x1bOS"
+	default:
+		// Управляющие сочетания (ctrl+a … ctrl+_) в bubbletea совпадают с их кодами
+		if msg.Type >= 0 && msg.Type < 0x20 {
+			out = string(rune(msg.Type))
+		}
+	}
+	if msg.Alt && out != "" {
+		out = "\x1b" + out
+	}
+	return []byte(out)
+}
+
+// shellSize — размер области вывода терминальной панели для шелла.
+func (m model) shellSize() (int, int) {
+	rows := m.terminalHeight
+	cols := m.width - 4 // рамка и отступы renderTerminal в режиме шелла
+	if rows < 1 {
+		rows = 1
+	}
+	if cols < 10 {
+		cols = 10
+	}
+	return rows, cols
+}
+
+// toggleShell включает/выключает режим шелла, запуская его при первом включении.
+func (m *model) toggleShell() tea.Cmd {
+	if m.shellMode {
+		m.shellMode = false
+		return nil
+	}
+	if m.shell == nil {
+		rows, cols := m.shellSize()
+		s, err := startShell(hostDir(m.activeDir()), rows, cols)
+		if err != nil {
+			m.termOutput.add("Shell: " + err.Error())
+			return nil
+		}
+		m.shell = s
+		m.shellMode = true
+		m.focusOnTerminal = true
+		m.termInput.Blur()
+		return s.waitShellOutput()
+	}
+	m.shellMode = true
+	m.focusOnTerminal = true
+	m.termInput.Blur()
+	return nil
+}
+
+// updateShellKey пересылает клавиши в шелл; свои остаются только переключатели фокуса.
+func (m model) updateShellKey(msg tea.KeyMsg) (model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+o":
+		m.shellMode = false
+		m.termInput.Focus()
+		return m, nil
+	case "alt+up", "alt+down":
+		m.focusOnTerminal = false
+		return m, nil
+	case "alt+left":
+		m.activePanel = 0
+		return m, nil
+	case "alt+right":
+		m.activePanel = 1
+		return m, nil
+	}
+	m.shell.write(keyBytes(msg, m.shell.screen.appCursor))
+	return m, nil
+}
+
+// handleShellOutput пропускает вывод через эмулятор и подтягивает панель
+// за текущим каталогом шелла.
+func (m *model) handleShellOutput(data []byte) tea.Cmd {
+	s := m.shell
+	s.screen.Write(data)
+	if len(s.screen.reply) > 0 {
+		s.write(s.screen.reply)
+		s.screen.reply = nil
+	}
+	if cwd, err := processCwd(s.cmd.Process.Pid); err == nil && cwd != s.syncedDir {
+		s.syncedDir = cwd
+		if cwd != hostDir(m.activeDir()) {
+			m.setActiveDir(cwd)
+		}
+	}
+	return s.waitShellOutput()
+}
+
+// activeDir — каталог активной панели.
+func (m model) activeDir() string {
+	if m.activePanel == 1 {
+		return m.rightDir
+	}
+	return m.leftDir
+}
+
+// setActiveDir переводит активную панель в каталог dir.
+func (m *model) setActiveDir(dir string) {
+	if m.activePanel == 0 {
+		m.leftDir = dir
+		m.reloadPanel(0)
+		m.leftCursor, m.leftScroll = 0, 0
+	} else {
+		m.rightDir = dir
+		m.reloadPanel(1)
+		m.rightCursor, m.rightScroll = 0, 0
+	}
+}
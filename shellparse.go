@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// pipelineStage — одна команда конвейера со своими перенаправлениями.
+type pipelineStage struct {
+	Args      []string
+	Stdin     string // < файл
+	Stdout    string // > или >> файл
+	AppendOut bool
+}
+
+// pipeline — разобранная командная строка: stage1 | stage2 | ... [&]
+type pipeline struct {
+	Source     string
+	Stages     []pipelineStage
+	Background bool // завершающий & — запустить как фоновое задание
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokPipe
+	tokIn
+	tokOut
+	tokAppend
+	tokBackground
+)
+
+// shellToken — слово или оператор. Для слова pattern — тот же текст, но с
+// экранированными спецсимволами glob из кавычек; glob=true, если в слове
+// есть незаключённые в кавычки *, ? или [.
+type shellToken struct {
+	kind    tokenKind
+	text    string
+	pattern string
+	glob    bool
+}
+
+// tokenize разбивает строку как POSIX-шелл: одинарные и двойные кавычки,
+// обратный слеш, $VAR и ${VAR}, ~ в начале слова и операторы | < > >> &.
+func tokenize(input string, lookup func(string) string) ([]shellToken, error) {
+	var tokens []shellToken
+	var text, pattern strings.Builder
+	inWord, glob := false, false
+
+	emit := func() {
+		if inWord {
+			tokens = append(tokens, shellToken{kind: tokWord, text: text.String(), pattern: pattern.String(), glob: glob})
+		}
+		text.Reset()
+		pattern.Reset()
+		inWord, glob = false, false
+	}
+	literal := func(s string) {
+		inWord = true
+		text.WriteString(s)
+		for _, r := range s {
+			if strings.ContainsRune(`*?[]\`, r) {
+				pattern.WriteByte('\\')
+			}
+			pattern.WriteRune(r)
+		}
+	}
+
+	runes := []rune(input)
+	// variable читает имя переменной после '$' начиная с позиции i и возвращает значение и новую позицию.
+	variable := func(i int) (string, int, error) {
+		if i < len(runes) && runes[i] == '{' {
+			for j := i + 1; j < len(runes); j++ {
+				if runes[j] == '}' {
+					return lookup(string(runes[i+1 : j])), j + 1, nil
+				}
+			}
+			return "", 0, fmt.Errorf("unterminated ${")
+		}
+		j := i
+		for j < len(runes) && (runes[j] == '_' || runes[j] >= 'a' && runes[j] <= 'z' || runes[j] >= 'A' && runes[j] <= 'Z' || j > i && runes[j] >= '0' && runes[j] <= '9') {
+			j++
+		}
+		if j == i {
+			return "$", i, nil
+		}
+		return lookup(string(runes[i:j])), j, nil
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t':
+			emit()
+		case r == '|':
+			emit()
+			tokens = append(tokens, shellToken{kind: tokPipe, text: "|"})
+		case r == '&':
+			emit()
+			tokens = append(tokens, shellToken{kind: tokBackground, text: "&"})
+		case r == '<':
+			emit()
+			tokens = append(tokens, shellToken{kind: tokIn, text: "<"})
+		case r == '>':
+			emit()
+			if i+1 < len(runes) && runes[i+1] == '>' {
+				i++
+				tokens = append(tokens, shellToken{kind: tokAppend, text: ">>"})
+			} else {
+				tokens = append(tokens, shellToken{kind: tokOut, text: ">"})
+			}
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				literal(string(runes[i]))
+			}
+		case r == '\'':
+			j := i + 1
+			for j < len(runes) && runes[j] != '\'' {
+				j++
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			literal(string(runes[i+1 : j]))
+			i = j
+		case r == '"':
+			inWord = true
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				switch {
+				case runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]):
+					i++
+					literal(string(runes[i]))
+				case runes[i] == '$':
+					val, next, err := variable(i + 1)
+					if err != nil {
+						return nil, err
+					}
+					literal(val)
+					i = next - 1
+				default:
+					literal(string(runes[i]))
+				}
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+		case r == '$':
+			val, next, err := variable(i + 1)
+			if err != nil {
+				return nil, err
+			}
+			literal(val)
+			i = next - 1
+		case r == '~' && !inWord && (i+1 == len(runes) || runes[i+1] == '/' || runes[i+1] == ' '):
+			literal(os.Getenv("HOME"))
+		case r == '*' || r == '?' || r == '[':
+			inWord = true
+			glob = true
+			text.WriteRune(r)
+			pattern.WriteRune(r)
+		default:
+			literal(string(r))
+		}
+	}
+	emit()
+	return tokens, nil
+}
+
+// expandGlob раскрывает шаблон относительно dir; без совпадений слово
+// остаётся как есть, как в bash.
+func expandGlob(tok shellToken, dir string) []string {
+	if !tok.glob {
+		return []string{tok.text}
+	}
+	pattern := tok.pattern
+	abs := filepath.IsAbs(pattern)
+	if !abs {
+		pattern = filepath.Join(dir, pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil || len(matches) == 0 {
+		return []string{tok.text}
+	}
+	sort.Strings(matches)
+	if !abs {
+		for i, m := range matches {
+			if rel, err := filepath.Rel(dir, m); err == nil {
+				matches[i] = rel
+			}
+		}
+	}
+	return matches
+}
+
+// parseCommandLine разбирает строку в конвейер, раскрывая переменные и glob.
+func parseCommandLine(input, dir string, lookup func(string) string) (*pipeline, error) {
+	tokens, err := tokenize(input, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pipeline{Source: input}
+	stage := pipelineStage{}
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t.kind {
+		case tokWord:
+			stage.Args = append(stage.Args, expandGlob(t, dir)...)
+		case tokPipe:
+			if len(stage.Args) == 0 {
+				return nil, fmt.Errorf("syntax error near |")
+			}
+			p.Stages = append(p.Stages, stage)
+			stage = pipelineStage{}
+		case tokBackground:
+			// && и & посреди строки не поддерживаются
+			if i != len(tokens)-1 {
+				return nil, fmt.Errorf("syntax error near &")
+			}
+			p.Background = true
+		case tokIn, tokOut, tokAppend:
+			if i+1 >= len(tokens) || tokens[i+1].kind != tokWord {
+				return nil, fmt.Errorf("syntax error near %s", t.text)
+			}
+			i++
+			target := tokens[i].text
+			if t.kind == tokIn {
+				stage.Stdin = target
+			} else {
+				stage.Stdout = target
+				stage.AppendOut = t.kind == tokAppend
+			}
+		}
+	}
+	if len(stage.Args) == 0 {
+		if len(p.Stages) > 0 {
+			return nil, fmt.Errorf("syntax error: missing command after |")
+		}
+		if stage.Stdin != "" || stage.Stdout != "" || p.Background {
+			return nil, fmt.Errorf("syntax error: missing command")
+		}
+		return p, nil
+	}
+	p.Stages = append(p.Stages, stage)
+
+	for i, s := range p.Stages {
+		if s.Stdin != "" && i > 0 {
+			return nil, fmt.Errorf("%s: ambiguous input redirect inside a pipeline", s.Args[0])
+		}
+		if s.Stdout != "" && i < len(p.Stages)-1 {
+			return nil, fmt.Errorf("%s: ambiguous output redirect inside a pipeline", s.Args[0])
+		}
+	}
+	return p, nil
+}
+
+// resolvePath — путь из перенаправления относительно рабочего каталога.
+func resolvePath(dir, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// startPipeline запускает стадии конвейера, соединяя их каналами. Вывод
+// последней стадии (если он не перенаправлен в файл) идёт в stdout, stderr
+// всех стадий — в stderr. Открытые для детей дескрипторы закрываются в
+// родителе после запуска.
+func startPipeline(ctx context.Context, p *pipeline, workingDir string, stdout, stderr *os.File) ([]*exec.Cmd, error) {
+	var cmds []*exec.Cmd
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	// abort останавливает уже запущенные стадии, если следующая не стартовала
+	abort := func(err error) ([]*exec.Cmd, error) {
+		for _, c := range cmds {
+			if c.Process != nil {
+				_ = c.Process.Kill()
+				_ = c.Wait()
+			}
+		}
+		return nil, err
+	}
+
+	var prev *os.File
+	last := len(p.Stages) - 1
+	for i, st := range p.Stages {
+		c := exec.CommandContext(ctx, st.Args[0], st.Args[1:]...)
+		c.Dir = workingDir
+		c.Stderr = stderr
+
+		if st.Stdin != "" {
+			f, err := os.Open(resolvePath(workingDir, st.Stdin))
+			if err != nil {
+				return abort(err)
+			}
+			files = append(files, f)
+			c.Stdin = f
+		} else if prev != nil {
+			c.Stdin = prev
+		}
+
+		switch {
+		case st.Stdout != "":
+			flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+			if st.AppendOut {
+				flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+			}
+			f, err := os.OpenFile(resolvePath(workingDir, st.Stdout), flags, 0644)
+			if err != nil {
+				return abort(err)
+			}
+			files = append(files, f)
+			c.Stdout = f
+		case i == last:
+			c.Stdout = stdout
+		default:
+			r, w, err := os.Pipe()
+			if err != nil {
+				return abort(err)
+			}
+			files = append(files, r, w)
+			c.Stdout = w
+			prev = r
+		}
+
+		if err := c.Start(); err != nil {
+			return abort(fmt.Errorf("%s: %w", st.Args[0], err))
+		}
+		cmds = append(cmds, c)
+	}
+	return cmds, nil
+}
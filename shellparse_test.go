@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testLookup(name string) string {
+	return map[string]string{"HOME": "/home/u", "X": "a b", "EMPTY": ""}[name]
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string // тексты токенов
+		glob  []bool
+	}{
+		{`cat file.txt`, []string{"cat", "file.txt"}, []bool{false, false}},
+		{`cat "my file.txt"`, []string{"cat", "my file.txt"}, []bool{false, false}},
+		{`cat 'it''s'`, []string{"cat", "its"}, []bool{false, false}},
+		{`cat my\ file`, []string{"cat", "my file"}, []bool{false, false}},
+		{`echo "$X" $X`, []string{"echo", "a b", "a b"}, []bool{false, false, false}},
+		{`echo ${HOME}/x '$HOME'`, []string{"echo", "/home/u/x", "$HOME"}, []bool{false, false, false}},
+		{`echo "a\"b" "\$X" "\n"`, []string{"echo", `a"b`, "$X", `\n`}, []bool{false, false, false, false}},
+		{`echo $ $1x`, []string{"echo", "$", "$1x"}, []bool{false, false, false}},
+		{`ls *.go "*.md" \?`, []string{"ls", "*.go", "*.md", "?"}, []bool{false, true, false, false}},
+		{`a|b>c>>d<e&`, []string{"a", "|", "b", ">", "c", ">>", "d", "<", "e", "&"}, nil},
+		{`echo ""`, []string{"echo", ""}, []bool{false, false}},
+		{`  spaced   out  `, []string{"spaced", "out"}, []bool{false, false}},
+	}
+	for _, tt := range tests {
+		tokens, err := tokenize(tt.input, testLookup)
+		if err != nil {
+			t.Errorf("tokenize(%q): %v", tt.input, err)
+			continue
+		}
+		var got []string
+		var glob []bool
+		for _, tok := range tokens {
+			got = append(got, tok.text)
+			glob = append(glob, tok.glob)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+		if tt.glob != nil && !reflect.DeepEqual(glob, tt.glob) {
+			t.Errorf("tokenize(%q) glob = %v, want %v", tt.input, glob, tt.glob)
+		}
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	for _, input := range []string{`echo 'open`, `echo "open`, `echo ${X`} {
+		if _, err := tokenize(input, testLookup); err == nil {
+			t.Errorf("tokenize(%q): expected an error", input)
+		}
+	}
+}
+
+func TestParseCommandLine(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.go", "a.go", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input string
+		want  pipeline
+	}{
+		{"ls *.go", pipeline{Stages: []pipelineStage{{Args: []string{"ls", "a.go", "b.go"}}}}},
+		{"ls *.none", pipeline{Stages: []pipelineStage{{Args: []string{"ls", "*.none"}}}}},
+		{"cat < in.txt | sort >> 'out file'", pipeline{Stages: []pipelineStage{
+			{Args: []string{"cat"}, Stdin: "in.txt"},
+			{Args: []string{"sort"}, Stdout: "out file", AppendOut: true},
+		}}},
+		{"mpv x.mkv &", pipeline{Stages: []pipelineStage{{Args: []string{"mpv", "x.mkv"}}}, Background: true}},
+		{"", pipeline{}},
+	}
+	for _, tt := range tests {
+		p, err := parseCommandLine(tt.input, dir, testLookup)
+		if err != nil {
+			t.Errorf("parseCommandLine(%q): %v", tt.input, err)
+			continue
+		}
+		tt.want.Source = tt.input
+		if !reflect.DeepEqual(*p, tt.want) {
+			t.Errorf("parseCommandLine(%q) = %+v, want %+v", tt.input, *p, tt.want)
+		}
+	}
+
+	for _, input := range []string{
+		"| ls", "ls |", "ls >", "ls > | x", "a & b", "> out", "ls > x | sort", "ls | sort < x",
+	} {
+		if _, err := parseCommandLine(input, dir, testLookup); err == nil {
+			t.Errorf("parseCommandLine(%q): expected a syntax error", input)
+		}
+	}
+}
@@ -0,0 +1,549 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type syncMode int
+
+const (
+	syncLeftToRight syncMode = iota
+	syncRightToLeft
+	syncBidirectional
+)
+
+func (s syncMode) String() string {
+	switch s {
+	case syncLeftToRight:
+		return "mirror left → right"
+	case syncRightToLeft:
+		return "mirror right → left"
+	}
+	return "bidirectional"
+}
+
+type syncActionKind int
+
+const (
+	syncCopy syncActionKind = iota
+	syncMkdir
+	syncDelete
+	syncConflict // только для показа в плане, не выполняется
+)
+
+type syncAction struct {
+	Kind syncActionKind
+	Rel  string // путь относительно корня синхронизации
+	Src  string
+	Dst  string
+	Note string
+}
+
+func (a syncAction) String() string {
+	switch a.Kind {
+	case syncCopy:
+		return fmt.Sprintf("copy    %s  (%s)", a.Rel, a.Note)
+	case syncMkdir:
+		return fmt.Sprintf("mkdir   %s  (%s)", a.Rel, a.Note)
+	case syncDelete:
+		return fmt.Sprintf("delete  %s  (%s)", a.Rel, a.Note)
+	}
+	return fmt.Sprintf("skip    %s  (%s)", a.Rel, a.Note)
+}
+
+type syncOptions struct {
+	Mode             syncMode
+	DeleteExtraneous bool
+	NewerWins        bool
+	Excludes         []string
+}
+
+// syncWizard — состояние диалога синхронизации: настройки, затем dry-run план.
+type syncWizard struct {
+	opts         syncOptions
+	field        int // 0 mode, 1 delete, 2 newer wins, 3 excludes
+	excludeInput textinput.Model
+
+	planning bool
+	plan     []syncAction
+	scroll   int
+	err      error
+}
+
+// syncRun — выполняемый план синхронизации.
+type syncRun struct {
+	plan   []syncAction
+	index  int
+	failed int
+}
+
+type syncPlanMsg struct {
+	Plan []syncAction
+	Err  error
+}
+
+type syncStepMsg struct {
+	Index int
+	Err   error
+}
+
+type syncEntry struct {
+	isDir   bool
+	size    int64
+	modTime time.Time
+}
+
+func newSyncWizard() *syncWizard {
+	ti := textinput.New()
+	ti.Placeholder = "*.tmp, .git, node_modules"
+	ti.CharLimit = 256
+	ti.Width = 40
+	return &syncWizard{
+		opts:         syncOptions{Mode: syncLeftToRight, NewerWins: true},
+		excludeInput: ti,
+	}
+}
+
+// parseExcludes разбирает список glob-шаблонов, разделённых запятыми или
+// переводами строк. Пробелы внутри шаблона сохраняются: "My Documents".
+func parseExcludes(s string) []string {
+	var out []string
+	for _, p := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func excluded(rel string, patterns []string) bool {
+	base := filepath.Base(rel)
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, base); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// scanSyncTree собирает все элементы дерева root, кроме исключённых.
+func scanSyncTree(root string, excludes []string) (map[string]syncEntry, error) {
+	entries := make(map[string]syncEntry)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if excluded(rel, excludes) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries[rel] = syncEntry{isDir: d.IsDir(), size: info.Size(), modTime: info.ModTime().Truncate(time.Second)}
+		return nil
+	})
+	return entries, err
+}
+
+// buildSyncPlanAsync строит dry-run план синхронизации двух каталогов.
+func buildSyncPlanAsync(leftDir, rightDir string, opts syncOptions) tea.Cmd {
+	return func() tea.Msg {
+		left, err := scanSyncTree(leftDir, opts.Excludes)
+		if err != nil {
+			return syncPlanMsg{Err: err}
+		}
+		right, err := scanSyncTree(rightDir, opts.Excludes)
+		if err != nil {
+			return syncPlanMsg{Err: err}
+		}
+
+		switch opts.Mode {
+		case syncLeftToRight:
+			return syncPlanMsg{Plan: planMirror(leftDir, rightDir, left, right, opts)}
+		case syncRightToLeft:
+			return syncPlanMsg{Plan: planMirror(rightDir, leftDir, right, left, opts)}
+		}
+		return syncPlanMsg{Plan: planBidirectional(leftDir, rightDir, left, right, opts)}
+	}
+}
+
+func sortedKeys(m map[string]syncEntry) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// Лексикографический порядок гарантирует, что каталог идёт раньше своего содержимого.
+	sort.Strings(keys)
+	return keys
+}
+
+// underDeleted сообщает, лежит ли rel внутри каталога, уже запланированного к удалению.
+func underDeleted(rel string, deleted []string) bool {
+	for _, d := range deleted {
+		if strings.HasPrefix(rel, d+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func planMirror(srcRoot, dstRoot string, src, dst map[string]syncEntry, opts syncOptions) []syncAction {
+	var plan []syncAction
+	// Каталоги цели, которые заменит файл, исчезают вместе с содержимым:
+	// удалять их детей отдельно уже нечего
+	var deleted []string
+	for _, rel := range sortedKeys(src) {
+		s := src[rel]
+		d, exists := dst[rel]
+		a := syncAction{Rel: rel, Src: filepath.Join(srcRoot, rel), Dst: filepath.Join(dstRoot, rel)}
+		switch {
+		case s.isDir && exists && d.isDir:
+			continue
+		case s.isDir:
+			a.Kind, a.Note = syncMkdir, "missing"
+			if exists {
+				a.Note = "replaces file"
+			}
+		case !exists:
+			a.Kind, a.Note = syncCopy, "missing"
+		case d.isDir:
+			a.Kind, a.Note = syncCopy, "replaces directory"
+			deleted = append(deleted, rel)
+		case s.size == d.size && s.modTime.Equal(d.modTime):
+			continue
+		case opts.NewerWins && d.modTime.After(s.modTime):
+			a.Kind, a.Note = syncConflict, "target is newer"
+		default:
+			a.Kind, a.Note = syncCopy, "changed"
+		}
+		plan = append(plan, a)
+	}
+
+	if opts.DeleteExtraneous {
+		for _, rel := range sortedKeys(dst) {
+			if _, ok := src[rel]; ok || underDeleted(rel, deleted) {
+				continue
+			}
+			plan = append(plan, syncAction{Kind: syncDelete, Rel: rel, Dst: filepath.Join(dstRoot, rel), Note: "extraneous"})
+			if dst[rel].isDir {
+				deleted = append(deleted, rel)
+			}
+		}
+	}
+	return plan
+}
+
+func planBidirectional(leftDir, rightDir string, left, right map[string]syncEntry, opts syncOptions) []syncAction {
+	var plan []syncAction
+	for _, rel := range sortedKeys(left) {
+		l := left[rel]
+		r, exists := right[rel]
+		toRight := syncAction{Rel: rel, Src: filepath.Join(leftDir, rel), Dst: filepath.Join(rightDir, rel)}
+		toLeft := syncAction{Rel: rel, Src: filepath.Join(rightDir, rel), Dst: filepath.Join(leftDir, rel)}
+		switch {
+		case !exists:
+			toRight.Kind, toRight.Note = syncCopy, "only left"
+			if l.isDir {
+				toRight.Kind = syncMkdir
+			}
+			plan = append(plan, toRight)
+		case l.isDir && r.isDir:
+		case l.isDir != r.isDir:
+			toRight.Kind, toRight.Note = syncConflict, "file/directory mismatch"
+			plan = append(plan, toRight)
+		case l.size == r.size && l.modTime.Equal(r.modTime):
+		case opts.NewerWins && l.modTime.After(r.modTime):
+			toRight.Kind, toRight.Note = syncCopy, "left newer"
+			plan = append(plan, toRight)
+		case opts.NewerWins && r.modTime.After(l.modTime):
+			toLeft.Kind, toLeft.Note = syncCopy, "right newer"
+			plan = append(plan, toLeft)
+		default:
+			toRight.Kind, toRight.Note = syncConflict, "changed on both sides"
+			plan = append(plan, toRight)
+		}
+	}
+	for _, rel := range sortedKeys(right) {
+		if _, ok := left[rel]; ok {
+			continue
+		}
+		a := syncAction{Kind: syncCopy, Rel: rel, Src: filepath.Join(rightDir, rel), Dst: filepath.Join(leftDir, rel), Note: "only right"}
+		if right[rel].isDir {
+			a.Kind = syncMkdir
+		}
+		plan = append(plan, a)
+	}
+	return plan
+}
+
+// runSyncStep выполняет одно действие плана через copyFile, сохраняя mtime источника.
+func runSyncStep(index int, a syncAction) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		switch a.Kind {
+		case syncMkdir:
+			if fi, statErr := os.Lstat(a.Dst); statErr == nil && !fi.IsDir() {
+				err = os.Remove(a.Dst)
+			}
+			if err == nil {
+				err = os.MkdirAll(a.Dst, 0755)
+			}
+		case syncCopy:
+			if fi, statErr := os.Lstat(a.Dst); statErr == nil && fi.IsDir() {
+				err = os.RemoveAll(a.Dst)
+			}
+			if err == nil {
+				err = copyFile(a.Src, a.Dst)
+			}
+			if err == nil {
+				if fi, statErr := os.Stat(a.Src); statErr == nil {
+					err = os.Chtimes(a.Dst, fi.ModTime(), fi.ModTime())
+				}
+			}
+		case syncDelete:
+			err = os.RemoveAll(a.Dst)
+		}
+		return syncStepMsg{Index: index, Err: err}
+	}
+}
+
+// nextSyncStep запускает следующее выполнимое действие или возвращает nil, если план закончен.
+func (m *model) nextSyncStep() tea.Cmd {
+	r := m.syncRun
+	for r.index < len(r.plan) && r.plan[r.index].Kind == syncConflict {
+		r.index++
+	}
+	if r.index >= len(r.plan) {
+		return nil
+	}
+	a := r.plan[r.index]
+	m.copying = true
+	m.copyFile = a.Rel
+	m.copyPercent = r.index * 100 / len(r.plan)
+	return runSyncStep(r.index, a)
+}
+
+func (m model) updateSyncWizard(msg tea.KeyMsg) (model, tea.Cmd) {
+	w := m.syncWizard
+	key := msg.String()
+
+	// Экран предпросмотра плана
+	if w.plan != nil || w.err != nil {
+		visible := m.syncPlanVisible()
+		switch key {
+		case "esc":
+			w.plan, w.err, w.scroll = nil, nil, 0
+		case "up", "k":
+			if w.scroll > 0 {
+				w.scroll--
+			}
+		case "down", "j":
+			if w.scroll < len(w.plan)-visible {
+				w.scroll++
+			}
+		case "pgup":
+			w.scroll -= visible
+			if w.scroll < 0 {
+				w.scroll = 0
+			}
+		case "pgdown":
+			w.scroll += visible
+			if w.scroll > len(w.plan)-visible {
+				w.scroll = max(len(w.plan)-visible, 0)
+			}
+		case "enter":
+			if w.err != nil || len(w.plan) == 0 {
+				m.syncWizard = nil
+				return m, nil
+			}
+			m.syncWizard = nil
+			m.syncRun = &syncRun{plan: w.plan}
+			m.termOutput.add(fmt.Sprintf("Sync started: %d actions (%s)", len(w.plan), w.opts.Mode))
+			cmd := m.nextSyncStep()
+			if cmd == nil {
+				m.finishSync()
+			}
+			return m, cmd
+		}
+		return m, nil
+	}
+
+	if w.planning {
+		if key == "esc" {
+			m.syncWizard = nil
+		}
+		return m, nil
+	}
+
+	switch key {
+	case "esc":
+		m.syncWizard = nil
+		return m, nil
+	case "up", "shift+tab":
+		w.field = (w.field + 3) % 4
+	case "down", "tab":
+		w.field = (w.field + 1) % 4
+	case "enter":
+		w.opts.Excludes = parseExcludes(w.excludeInput.Value())
+		w.planning = true
+		return m, buildSyncPlanAsync(m.leftDir, m.rightDir, w.opts)
+	default:
+		switch w.field {
+		case 0:
+			switch key {
+			case "left":
+				w.opts.Mode = (w.opts.Mode + 2) % 3
+			case "right", " ":
+				w.opts.Mode = (w.opts.Mode + 1) % 3
+			}
+		case 1:
+			if key == " " || key == "left" || key == "right" {
+				w.opts.DeleteExtraneous = !w.opts.DeleteExtraneous
+			}
+		case 2:
+			if key == " " || key == "left" || key == "right" {
+				w.opts.NewerWins = !w.opts.NewerWins
+			}
+		case 3:
+			var cmd tea.Cmd
+			w.excludeInput, cmd = w.excludeInput.Update(msg)
+			return m, cmd
+		}
+	}
+
+	if w.field == 3 {
+		return m, w.excludeInput.Focus()
+	}
+	w.excludeInput.Blur()
+	return m, nil
+}
+
+func (m *model) finishSync() {
+	r := m.syncRun
+	m.syncRun = nil
+	m.copying = false
+	m.termOutput.add(fmt.Sprintf("Sync finished: %d failed.", r.failed))
+	m.reloadPanel(0)
+	m.reloadPanel(1)
+	m.flashMessage = "Sync finished"
+	m.flashTimer = time.Now()
+}
+
+// syncPlanVisible — сколько строк плана помещается в окне предпросмотра.
+func (m model) syncPlanVisible() int {
+	n := m.height - 12
+	if n < 3 {
+		n = 3
+	}
+	return n
+}
+
+func (m model) renderSyncWizard() string {
+	w := m.syncWizard
+	popupWidth := m.width - 10
+	if popupWidth > 100 {
+		popupWidth = 100
+	}
+	if popupWidth < 40 {
+		popupWidth = 40
+	}
+
+	popupStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("171")).
+		Padding(1, 2).
+		Width(popupWidth)
+
+	title := lipgloss.NewStyle().Bold(true).Render("Synchronize " + filepath.Base(m.leftDir) + " ⇄ " + filepath.Base(m.rightDir))
+	hint := lipgloss.NewStyle().Faint(true)
+
+	var b strings.Builder
+	switch {
+	case w.err != nil:
+		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("Error: " + w.err.Error()))
+		b.WriteString("\n\n" + hint.Render("Esc back • Enter close"))
+	case w.plan != nil:
+		runnable := 0
+		for _, a := range w.plan {
+			if a.Kind != syncConflict {
+				runnable++
+			}
+		}
+		b.WriteString(fmt.Sprintf("Dry run: %d actions, %d skipped\n\n", runnable, len(w.plan)-runnable))
+		if len(w.plan) == 0 {
+			b.WriteString("Nothing to do — directories are in sync.\n")
+		}
+		end := w.scroll + m.syncPlanVisible()
+		if end > len(w.plan) {
+			end = len(w.plan)
+		}
+		for _, a := range w.plan[w.scroll:end] {
+			line := a.String()
+			switch a.Kind {
+			case syncDelete:
+				line = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(line)
+			case syncConflict:
+				line = lipgloss.NewStyle().Faint(true).Render(line)
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("\n" + hint.Render("↑/↓ scroll • Enter execute • Esc back"))
+	case w.planning:
+		b.WriteString("Building plan...")
+	default:
+		fields := []string{
+			"Mode:            " + w.opts.Mode.String(),
+			"Delete extra:    " + checkbox(w.opts.DeleteExtraneous),
+			"Newer wins:      " + checkbox(w.opts.NewerWins),
+			"Exclude:         " + w.excludeInput.View(),
+		}
+		for i, f := range fields {
+			if i == w.field {
+				b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("171")).Bold(true).Render("● " + f))
+			} else {
+				b.WriteString("  " + f)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n" + hint.Render("↑/↓ field • Space/←/→ change • Enter dry run • Esc cancel"))
+	}
+
+	popup := popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, title, "", b.String()))
+
+	x := (m.width - popupWidth) / 2
+	if x < 0 {
+		x = 0
+	}
+	return lipgloss.NewStyle().MarginLeft(x).MarginTop(1).Render(popup)
+}
+
+func checkbox(on bool) string {
+	if on {
+		return "[x]"
+	}
+	return "[ ]"
+}
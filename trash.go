@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// trashDir — корзина пользователя по спецификации freedesktop.org.
+func trashDir() string {
+	return filepath.Join(xdgDir("XDG_DATA_HOME", ".local/share"), "Trash")
+}
+
+// moveToTrash переносит файл или каталог в корзину, записывая .trashinfo,
+// чтобы файловые менеджеры могли его восстановить.
+func moveToTrash(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(abs); err != nil {
+		return err
+	}
+	files := filepath.Join(trashDir(), "files")
+	info := filepath.Join(trashDir(), "info")
+	if err := os.MkdirAll(files, 0700); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(info, 0700); err != nil {
+		return err
+	}
+
+	// Имя в корзине должно быть уникальным: занимаем его созданием .trashinfo
+	base := filepath.Base(abs)
+	var name string
+	var infoFile *os.File
+	for i := 1; ; i++ {
+		name = base
+		if i > 1 {
+			name = base + "." + strconv.Itoa(i)
+		}
+		infoFile, err = os.OpenFile(filepath.Join(info, name+".trashinfo"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(infoFile, "[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: abs}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+	if cerr := infoFile.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(infoFile.Name())
+		return err
+	}
+
+	dst := filepath.Join(files, name)
+	if err := moveFile(abs, dst); err != nil {
+		os.Remove(infoFile.Name())
+		return err
+	}
+	return nil
+}
+
+// moveFile переименовывает src в dst, а между файловыми системами
+// копирует и удаляет оригинал.
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyForMove(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyForMove копирует дерево как copyFile, но символические ссылки
+// воссоздаёт, а не копирует их цели: перенос не должен менять ссылку на
+// копию файла, а исходную цель — терять.
+func copyForMove(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case info.IsDir():
+		if err := os.MkdirAll(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := copyForMove(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return copyFile(src, dst)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Архивы открываются в панели как каталоги только для чтения. Путь внутри
+// архива записывается как обычный: /home/u/src.zip/pkg/file.go — так
+// навигация, буфер c/p и остальной код работают с ним без изменений, а
+// список берётся из оглавления архива вместо os.ReadDir.
+
+// splitArchivePath делит путь на файл архива и путь внутри него ("" —
+// корень архива). ok == false для обычных путей.
+func splitArchivePath(p string) (archive, inner string, ok bool) {
+	p = filepath.Clean(p)
+	for dir := p; ; dir = filepath.Dir(dir) {
+		if formatByName(dir) >= 0 {
+			if info, err := os.Stat(dir); err == nil && info.Mode().IsRegular() {
+				rel, _ := filepath.Rel(dir, p)
+				if rel == "." {
+					rel = ""
+				}
+				return dir, filepath.ToSlash(rel), true
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			return "", "", false
+		}
+	}
+}
+
+// isArchiveDir — каталог панели находится в архиве, включая его корень.
+func isArchiveDir(dir string) bool {
+	_, _, ok := splitArchivePath(dir)
+	return ok
+}
+
+// isArchiveMember — путь к элементу внутри архива, а не к самому архиву.
+func isArchiveMember(p string) bool {
+	_, inner, ok := splitArchivePath(p)
+	return ok && inner != ""
+}
+
+// hostDir — настоящий каталог для команд и шелла: для пути внутри архива
+// это каталог, где лежит архив.
+func hostDir(dir string) string {
+	if archive, _, ok := splitArchivePath(dir); ok {
+		return filepath.Dir(archive)
+	}
+	return dir
+}
+
+// archiveIndex — оглавление архива.
+type archiveIndex struct {
+	entries  map[string]archiveEntry
+	children map[string][]string // имена в каталоге; "" — корень
+}
+
+type cachedIndex struct {
+	modTime time.Time
+	size    int64
+	index   *archiveIndex
+	err     error
+}
+
+// Оглавления кешируются до изменения файла архива.
+var archiveIndexes = struct {
+	sync.Mutex
+	m map[string]cachedIndex
+}{m: make(map[string]cachedIndex)}
+
+func openArchiveIndex(archive string) (*archiveIndex, error) {
+	info, err := os.Stat(archive)
+	if err != nil {
+		return nil, err
+	}
+	archiveIndexes.Lock()
+	c, ok := archiveIndexes.m[archive]
+	archiveIndexes.Unlock()
+	if ok && c.modTime.Equal(info.ModTime()) && c.size == info.Size() {
+		return c.index, c.err
+	}
+
+	idx := &archiveIndex{entries: make(map[string]archiveEntry), children: make(map[string][]string)}
+	err = walkArchive(context.Background(), archive, false, func(e archiveEntry, _ io.Reader) error {
+		if e.name != "" {
+			idx.add(e)
+		}
+		return nil
+	})
+	archiveIndexes.Lock()
+	archiveIndexes.m[archive] = cachedIndex{modTime: info.ModTime(), size: info.Size(), index: idx, err: err}
+	archiveIndexes.Unlock()
+	return idx, err
+}
+
+// add заносит элемент и недостающие родительские каталоги: в архивах
+// они часто не записаны отдельно.
+func (idx *archiveIndex) add(e archiveEntry) {
+	if _, exists := idx.entries[e.name]; !exists {
+		parent := path.Dir(e.name)
+		if parent == "." {
+			parent = ""
+		}
+		idx.children[parent] = append(idx.children[parent], path.Base(e.name))
+		if parent != "" {
+			if _, ok := idx.entries[parent]; !ok {
+				idx.add(archiveEntry{name: parent, raw: parent, info: virtualDir(path.Base(parent))})
+			}
+		}
+	}
+	if old, exists := idx.entries[e.name]; exists && !e.info.IsDir() && old.info.IsDir() {
+		return
+	}
+	idx.entries[e.name] = e
+}
+
+// size — сумма размеров файлов элемента inner со всем содержимым.
+func (idx *archiveIndex) size(inner string) int64 {
+	var total int64
+	for name, e := range idx.entries {
+		if inner == "" || name == inner || strings.HasPrefix(name, inner+"/") {
+			if e.info.Mode().IsRegular() {
+				total += e.info.Size()
+			}
+		}
+	}
+	return total
+}
+
+// virtualDir — FileInfo каталога, который есть в архиве только неявно.
+type virtualDir string
+
+func (d virtualDir) Name() string       { return string(d) }
+func (d virtualDir) Size() int64        { return 0 }
+func (d virtualDir) Mode() fs.FileMode  { return fs.ModeDir | 0755 }
+func (d virtualDir) ModTime() time.Time { return time.Time{} }
+func (d virtualDir) IsDir() bool        { return true }
+func (d virtualDir) Sys() any           { return nil }
+
+// archiveDirItems — список каталога внутри архива в виде getDirItems.
+func archiveDirItems(archive, inner string, showHidden bool) []string {
+	idx, err := openArchiveIndex(archive)
+	if err != nil {
+		return []string{"Error: " + err.Error()}
+	}
+	if e, ok := idx.entries[inner]; inner != "" && (!ok || !e.info.IsDir()) {
+		return []string{"Error: " + inner + ": not a directory in " + filepath.Base(archive)}
+	}
+	var items []string
+	for _, name := range idx.children[inner] {
+		if !showHidden && strings.HasPrefix(name, ".") {
+			continue
+		}
+		items = append(items, name)
+	}
+	sort.Strings(items)
+	return items
+}
+
+// statPath — os.Stat, понимающий пути внутри архивов.
+func statPath(p string) (fs.FileInfo, error) {
+	archive, inner, ok := splitArchivePath(p)
+	if !ok || inner == "" {
+		return os.Stat(p)
+	}
+	idx, err := openArchiveIndex(archive)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := idx.entries[inner]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+	}
+	return e.info, nil
+}
+
+// canEnter — в элемент можно войти панелью: каталог или архив.
+func canEnter(p string) bool {
+	info, err := statPath(p)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		return true
+	}
+	_, inner, ok := splitArchivePath(p)
+	return ok && inner == ""
+}
+
+// viewTemp — общий на сессию временный каталог для элементов архивов,
+// открытых программами; удаляется при выходе (cleanupViewTemp).
+var viewTemp struct {
+	sync.Mutex
+	root string
+}
+
+// refuseInArchive сообщает, что action невозможно в панели внутри архива,
+// и возвращает true, если это так: архивы открываются только для чтения.
+func (m *model) refuseInArchive(action string) bool {
+	if !isArchiveDir(m.activeDir()) {
+		return false
+	}
+	m.termOutput.add(action + ": archives are read-only, open a regular directory.")
+	return true
+}
+
+// localPath возвращает путь к файлу на диске; элемент архива для этого
+// распаковывается во временный каталог.
+func localPath(p string) (string, error) {
+	archive, inner, ok := splitArchivePath(p)
+	if !ok || inner == "" {
+		return p, nil
+	}
+	viewTemp.Lock()
+	if viewTemp.root == "" {
+		root, err := os.MkdirTemp("", "nddtc2-view-*")
+		if err != nil {
+			viewTemp.Unlock()
+			return "", err
+		}
+		viewTemp.root = root
+	}
+	root := viewTemp.root
+	viewTemp.Unlock()
+	// Отдельный подкаталог: одноимённые файлы из разных архивов не мешают друг другу
+	dir, err := os.MkdirTemp(root, "")
+	if err != nil {
+		return "", err
+	}
+	local := filepath.Join(dir, path.Base(inner))
+	if err := extractArchive(context.Background(), archive, inner, local, false, nil); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("extract %s: %w", inner, err)
+	}
+	return local, nil
+}
+
+// cleanupViewTemp удаляет файлы, распакованные для просмотра.
+func cleanupViewTemp() {
+	viewTemp.Lock()
+	defer viewTemp.Unlock()
+	if viewTemp.root != "" {
+		os.RemoveAll(viewTemp.root)
+		viewTemp.root = ""
+	}
+}
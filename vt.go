@@ -0,0 +1,578 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// vtStyle — атрибуты ячейки экрана. Цвета: -1 — по умолчанию,
+// 0..255 — палитра, значения с флагом vtTrueColor — 24-битный RGB.
+type vtStyle struct {
+	fg, bg    int
+	bold      bool
+	faint     bool
+	italic    bool
+	underline bool
+	reverse   bool
+}
+
+const vtTrueColor = 1 << 24
+
+var vtDefaultStyle = vtStyle{fg: -1, bg: -1}
+
+type vtCell struct {
+	r     rune
+	style vtStyle
+}
+
+type vtState int
+
+const (
+	vtGround vtState = iota
+	vtEscape
+	vtCSI
+	vtOSC
+	vtOSCEscape
+	vtCharset
+)
+
+// vtScreen — минимальный эмулятор VT100/xterm: достаточно для шелла,
+// less, top и полноэкранных редакторов.
+type vtScreen struct {
+	rows, cols int
+	cells      [][]vtCell
+	primary    [][]vtCell // основной буфер, пока активен альтернативный экран
+
+	curX, curY     int
+	savedX, savedY int
+	wrapPending    bool
+	cursorHidden   bool
+	appCursor      bool // DECCKM: стрелки шлются как ESC O A
+	style          vtStyle
+	top, bottom    int // область прокрутки (включительно)
+
+	state   vtState
+	params  []byte
+	partial []byte // незавершённая UTF-8 последовательность
+
+	// reply копит ответы терминала (например, на запрос позиции курсора),
+	// владелец пишет их обратно в pty.
+	reply []byte
+}
+
+func newVTScreen(rows, cols int) *vtScreen {
+	s := &vtScreen{style: vtDefaultStyle}
+	s.Resize(rows, cols)
+	return s
+}
+
+func blankRow(cols int) []vtCell {
+	row := make([]vtCell, cols)
+	for i := range row {
+		row[i] = vtCell{r: ' ', style: vtDefaultStyle}
+	}
+	return row
+}
+
+// Resize меняет размер экрана, сохраняя видимое содержимое снизу.
+func (s *vtScreen) Resize(rows, cols int) {
+	if rows < 1 {
+		rows = 1
+	}
+	if cols < 1 {
+		cols = 1
+	}
+	if rows == s.rows && cols == s.cols {
+		return
+	}
+	resize := func(old [][]vtCell) [][]vtCell {
+		cells := make([][]vtCell, rows)
+		shift := 0
+		if len(old) > rows && s.curY >= rows {
+			shift = s.curY - rows + 1
+		}
+		for y := range cells {
+			cells[y] = blankRow(cols)
+			if oy := y + shift; oy < len(old) {
+				copy(cells[y], old[oy])
+			}
+		}
+		return cells
+	}
+	if s.primary != nil {
+		s.primary = resize(s.primary)
+	}
+	shift := 0
+	if s.curY >= rows {
+		shift = s.curY - rows + 1
+	}
+	s.cells = resize(s.cells)
+	s.rows, s.cols = rows, cols
+	s.curY -= shift
+	s.top, s.bottom = 0, rows-1
+	s.clampCursor()
+}
+
+func (s *vtScreen) clampCursor() {
+	if s.curX >= s.cols {
+		s.curX = s.cols - 1
+	}
+	if s.curY >= s.rows {
+		s.curY = s.rows - 1
+	}
+	if s.curX < 0 {
+		s.curX = 0
+	}
+	if s.curY < 0 {
+		s.curY = 0
+	}
+}
+
+// Write разбирает поток байт от программы.
+func (s *vtScreen) Write(data []byte) {
+	if len(s.partial) > 0 {
+		data = append(s.partial, data...)
+		s.partial = nil
+	}
+	for len(data) > 0 {
+		b := data[0]
+		if s.state == vtGround && b >= 0x80 {
+			if !utf8.FullRune(data) {
+				s.partial = append([]byte(nil), data...)
+				return
+			}
+			r, size := utf8.DecodeRune(data)
+			s.put(r)
+			data = data[size:]
+			continue
+		}
+		s.feed(b)
+		data = data[1:]
+	}
+}
+
+func (s *vtScreen) feed(b byte) {
+	switch s.state {
+	case vtEscape:
+		s.escape(b)
+		return
+	case vtCSI:
+		if b >= 0x40 && b <= 0x7e {
+			s.csi(b)
+			s.state = vtGround
+		} else {
+			s.params = append(s.params, b)
+		}
+		return
+	case vtOSC:
+		switch b {
+		case 0x07:
+			s.state = vtGround
+		case 0x1b:
+			s.state = vtOSCEscape
+		}
+		return
+	case vtOSCEscape:
+		s.state = vtGround
+		return
+	case vtCharset:
+		s.state = vtGround
+		return
+	}
+
+	switch b {
+	case 0x1b:
+		s.state = vtEscape
+	case '\r':
+		s.curX = 0
+		s.wrapPending = false
+	case '\n', 0x0b, 0x0c:
+		s.lineFeed()
+	case '\b':
+		if s.curX > 0 {
+			s.curX--
+		}
+		s.wrapPending = false
+	case '\t':
+		s.curX = (s.curX/8 + 1) * 8
+		if s.curX >= s.cols {
+			s.curX = s.cols - 1
+		}
+	case 0x07, 0x00, 0x0e, 0x0f:
+	default:
+		if b >= 0x20 {
+			s.put(rune(b))
+		}
+	}
+}
+
+func (s *vtScreen) escape(b byte) {
+	s.state = vtGround
+	switch b {
+	case '[':
+		s.state = vtCSI
+		s.params = s.params[:0]
+	case ']':
+		s.state = vtOSC
+	case '(', ')', '*', '+', '#':
+		s.state = vtCharset
+	case '7':
+		s.savedX, s.savedY = s.curX, s.curY
+	case '8':
+		s.curX, s.curY = s.savedX, s.savedY
+		s.clampCursor()
+	case 'D':
+		s.lineFeed()
+	case 'E':
+		s.curX = 0
+		s.lineFeed()
+	case 'M':
+		if s.curY == s.top {
+			s.scrollDown(1)
+		} else if s.curY > 0 {
+			s.curY--
+		}
+	case 'c':
+		rows, cols := s.rows, s.cols
+		*s = vtScreen{style: vtDefaultStyle}
+		s.Resize(rows, cols)
+	}
+}
+
+func (s *vtScreen) put(r rune) {
+	if s.wrapPending {
+		s.curX = 0
+		s.lineFeed()
+		s.wrapPending = false
+	}
+	s.cells[s.curY][s.curX] = vtCell{r: r, style: s.style}
+	if s.curX == s.cols-1 {
+		s.wrapPending = true
+	} else {
+		s.curX++
+	}
+}
+
+func (s *vtScreen) lineFeed() {
+	s.wrapPending = false
+	if s.curY == s.bottom {
+		s.scrollUp(1)
+	} else if s.curY < s.rows-1 {
+		s.curY++
+	}
+}
+
+// scrollUp и scrollDown сдвигают область прокрутки; сдвиг больше её
+// высоты просто очищает её, так что n ограничивается высотой.
+func (s *vtScreen) scrollUp(n int) {
+	n = min(n, s.bottom-s.top+1)
+	for i := 0; i < n; i++ {
+		copy(s.cells[s.top:s.bottom], s.cells[s.top+1:s.bottom+1])
+		s.cells[s.bottom] = blankRow(s.cols)
+	}
+}
+
+func (s *vtScreen) scrollDown(n int) {
+	n = min(n, s.bottom-s.top+1)
+	for i := 0; i < n; i++ {
+		copy(s.cells[s.top+1:s.bottom+1], s.cells[s.top:s.bottom])
+		s.cells[s.top] = blankRow(s.cols)
+	}
+}
+
+func (s *vtScreen) csiParams(def int) []int {
+	raw := strings.TrimLeft(string(s.params), "?>=!")
+	if raw == "" {
+		return []int{def}
+	}
+	var out []int
+	for _, p := range strings.Split(raw, ";") {
+		n, err := strconv.Atoi(p)
+		// Отрицательные параметры в CSI не бывают: мусор в выводе не должен
+		// выводить индексы за пределы экрана
+		if err != nil || n < 0 || (n == 0 && def != 0) {
+			n = def
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+func (s *vtScreen) eraseCells(y, from, to int) {
+	for x := from; x < to && x < s.cols; x++ {
+		s.cells[y][x] = vtCell{r: ' ', style: vtStyle{fg: -1, bg: s.style.bg}}
+	}
+}
+
+func (s *vtScreen) csi(final byte) {
+	private := len(s.params) > 0 && s.params[0] == '?'
+	p := s.csiParams(1)
+	n := p[0]
+	s.wrapPending = false
+
+	switch final {
+	case 'A':
+		s.curY -= n
+	case 'B', 'e':
+		s.curY += n
+	case 'C', 'a':
+		s.curX += n
+	case 'D':
+		s.curX -= n
+	case 'E':
+		s.curX, s.curY = 0, s.curY+n
+	case 'F':
+		s.curX, s.curY = 0, s.curY-n
+	case 'G', '`':
+		s.curX = n - 1
+	case 'd':
+		s.curY = n - 1
+	case 'H', 'f':
+		s.curY = n - 1
+		s.curX = 0
+		if len(p) > 1 {
+			s.curX = p[1] - 1
+		}
+	case 'J':
+		switch s.csiParams(0)[0] {
+		case 0:
+			s.eraseCells(s.curY, s.curX, s.cols)
+			for y := s.curY + 1; y < s.rows; y++ {
+				s.eraseCells(y, 0, s.cols)
+			}
+		case 1:
+			s.eraseCells(s.curY, 0, s.curX+1)
+			for y := 0; y < s.curY; y++ {
+				s.eraseCells(y, 0, s.cols)
+			}
+		default:
+			for y := 0; y < s.rows; y++ {
+				s.eraseCells(y, 0, s.cols)
+			}
+		}
+	case 'K':
+		switch s.csiParams(0)[0] {
+		case 0:
+			s.eraseCells(s.curY, s.curX, s.cols)
+		case 1:
+			s.eraseCells(s.curY, 0, s.curX+1)
+		default:
+			s.eraseCells(s.curY, 0, s.cols)
+		}
+	case 'X':
+		s.eraseCells(s.curY, s.curX, s.curX+n)
+	case 'P':
+		row := s.cells[s.curY]
+		n = max(1, min(n, s.cols-s.curX))
+		copy(row[s.curX:], row[s.curX+n:])
+		s.eraseCells(s.curY, s.cols-n, s.cols)
+	case '@':
+		row := s.cells[s.curY]
+		n = max(1, min(n, s.cols-s.curX))
+		copy(row[s.curX+n:], row[s.curX:])
+		s.eraseCells(s.curY, s.curX, s.curX+n)
+	case 'L', 'M':
+		if s.curY < s.top || s.curY > s.bottom {
+			break
+		}
+		top := s.top
+		s.top = s.curY
+		if final == 'L' {
+			s.scrollDown(n)
+		} else {
+			s.scrollUp(n)
+		}
+		s.top = top
+	case 'S':
+		s.scrollUp(n)
+	case 'T':
+		s.scrollDown(n)
+	case 'r':
+		top, bottom := 1, s.rows
+		if len(s.params) > 0 {
+			rp := s.csiParams(1)
+			top = rp[0]
+			if len(rp) > 1 {
+				bottom = rp[1]
+			}
+		}
+		if top < 1 {
+			top = 1
+		}
+		if bottom > s.rows {
+			bottom = s.rows
+		}
+		if top < bottom {
+			s.top, s.bottom = top-1, bottom-1
+		}
+		s.curX, s.curY = 0, 0
+	case 's':
+		s.savedX, s.savedY = s.curX, s.curY
+	case 'u':
+		s.curX, s.curY = s.savedX, s.savedY
+	case 'm':
+		s.sgr()
+	case 'n':
+		if n == 6 {
+			s.reply = append(s.reply, fmt.Sprintf("\x1b[%d;%dR", s.curY+1, s.curX+1)...)
+		} else if n == 5 {
+			s.reply = append(s.reply, "\x1b[0n"...)
+		}
+	case 'c':
+		if !private && len(s.params) == 0 || string(s.params) == "0" {
+			s.reply = append(s.reply, "\x1b[?1;2c"...)
+		}
+	case 'h', 'l':
+		if private {
+			s.setMode(p, final == 'h')
+		}
+	}
+	s.clampCursor()
+}
+
+func (s *vtScreen) setMode(modes []int, on bool) {
+	for _, mode := range modes {
+		switch mode {
+		case 1:
+			s.appCursor = on
+		case 25:
+			s.cursorHidden = !on
+		case 47, 1047, 1049:
+			if on && s.primary == nil {
+				if mode == 1049 {
+					s.savedX, s.savedY = s.curX, s.curY
+				}
+				s.primary = s.cells
+				s.cells = make([][]vtCell, s.rows)
+				for y := range s.cells {
+					s.cells[y] = blankRow(s.cols)
+				}
+			} else if !on && s.primary != nil {
+				s.cells = s.primary
+				s.primary = nil
+				if mode == 1049 {
+					s.curX, s.curY = s.savedX, s.savedY
+				}
+			}
+		}
+	}
+}
+
+func (s *vtScreen) sgr() {
+	p := s.csiParams(0)
+	for i := 0; i < len(p); i++ {
+		switch v := p[i]; {
+		case v == 0:
+			s.style = vtDefaultStyle
+		case v == 1:
+			s.style.bold = true
+		case v == 2:
+			s.style.faint = true
+		case v == 3:
+			s.style.italic = true
+		case v == 4:
+			s.style.underline = true
+		case v == 7:
+			s.style.reverse = true
+		case v == 22:
+			s.style.bold, s.style.faint = false, false
+		case v == 23:
+			s.style.italic = false
+		case v == 24:
+			s.style.underline = false
+		case v == 27:
+			s.style.reverse = false
+		case v >= 30 && v <= 37:
+			s.style.fg = v - 30
+		case v == 39:
+			s.style.fg = -1
+		case v >= 40 && v <= 47:
+			s.style.bg = v - 40
+		case v == 49:
+			s.style.bg = -1
+		case v >= 90 && v <= 97:
+			s.style.fg = v - 90 + 8
+		case v >= 100 && v <= 107:
+			s.style.bg = v - 100 + 8
+		case v == 38 || v == 48:
+			color, used := extendedColor(p[i+1:])
+			i += used
+			if v == 38 {
+				s.style.fg = color
+			} else {
+				s.style.bg = color
+			}
+		}
+	}
+}
+
+// extendedColor разбирает "5;n" и "2;r;g;b" после 38/48.
+func extendedColor(p []int) (int, int) {
+	if len(p) >= 2 && p[0] == 5 {
+		return p[1], 2
+	}
+	if len(p) >= 4 && p[0] == 2 {
+		return vtTrueColor | p[1]<<16 | p[2]<<8 | p[3], 4
+	}
+	return -1, len(p)
+}
+
+func vtColor(c int) lipgloss.Color {
+	if c&vtTrueColor != 0 {
+		return lipgloss.Color(fmt.Sprintf("#%06x", c&0xffffff))
+	}
+	return lipgloss.Color(strconv.Itoa(c))
+}
+
+func (st vtStyle) render(text string) string {
+	if st == vtDefaultStyle {
+		return text
+	}
+	ls := lipgloss.NewStyle().
+		Bold(st.bold).
+		Faint(st.faint).
+		Italic(st.italic).
+		Underline(st.underline).
+		Reverse(st.reverse)
+	if st.fg >= 0 {
+		ls = ls.Foreground(vtColor(st.fg))
+	}
+	if st.bg >= 0 {
+		ls = ls.Background(vtColor(st.bg))
+	}
+	return ls.Render(text)
+}
+
+// Lines возвращает строки экрана со стилями; курсор рисуется инверсией, если showCursor.
+func (s *vtScreen) Lines(showCursor bool) []string {
+	lines := make([]string, s.rows)
+	for y, row := range s.cells {
+		var b strings.Builder
+		var run strings.Builder
+		runStyle := vtDefaultStyle
+		flush := func() {
+			if run.Len() > 0 {
+				b.WriteString(runStyle.render(run.String()))
+				run.Reset()
+			}
+		}
+		for x, c := range row {
+			st := c.style
+			if showCursor && !s.cursorHidden && x == s.curX && y == s.curY {
+				st.reverse = !st.reverse
+			}
+			if st != runStyle {
+				flush()
+				runStyle = st
+			}
+			run.WriteRune(c.r)
+		}
+		flush()
+		lines[y] = strings.TrimRight(b.String(), " ")
+	}
+	return lines
+}
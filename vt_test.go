@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// vtText — содержимое экрана без стилей, строки без хвостовых пробелов.
+func vtText(s *vtScreen) []string {
+	lines := make([]string, len(s.cells))
+	for y, row := range s.cells {
+		var b strings.Builder
+		for _, c := range row {
+			b.WriteRune(c.r)
+		}
+		lines[y] = strings.TrimRight(b.String(), " ")
+	}
+	return lines
+}
+
+func TestVTScreenEditing(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"text", "ab\r\ncd", []string{"ab", "cd", ""}},
+		{"wrap", "abcdefg", []string{"abcde", "fg", ""}},
+		{"position", "\x1b[2;3Hx", []string{"", "  x", ""}},
+		{"erase line", "abcde\x1b[1;3H\x1b[K", []string{"ab", "", ""}},
+		{"delete chars", "abcde\x1b[1;2H\x1b[2P", []string{"ade", "", ""}},
+		{"insert chars", "abcde\x1b[1;2H\x1b[2@", []string{"a  bc", "", ""}},
+		{"scroll", "1\r\n2\r\n3\r\n4", []string{"2", "3", "4"}},
+		{"utf8", "привет", []string{"приве", "т", ""}},
+	}
+	for _, tt := range tests {
+		s := newVTScreen(3, 5)
+		s.Write([]byte(tt.input))
+		if got := vtText(s); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: screen = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestVTScreenHostileCSI проверяет, что мусорные параметры не выводят
+// курсор и индексы за пределы экрана.
+func TestVTScreenHostileCSI(t *testing.T) {
+	for _, seq := range []string{
+		"\x1b[-5P", "\x1b[-5@", "\x1b[-1;-1H", "\x1b[999;999H", "\x1b[99999S",
+		"\x1b[99999T", "\x1b[99999L", "\x1b[99999M", "\x1b[99999X", "\x1b[-3A",
+		"\x1b[99999999999999999999C", "\x1b[5;2r", "\x1b[0;0r", "\x1b[;H",
+	} {
+		s := newVTScreen(4, 6)
+		s.Write([]byte("abc\r\ndef"))
+		s.Write([]byte(seq))
+		s.Write([]byte("z"))
+		if s.curX < 0 || s.curX >= s.cols || s.curY < 0 || s.curY >= s.rows {
+			t.Errorf("%q: cursor out of screen at %d,%d", seq, s.curX, s.curY)
+		}
+		if len(s.cells) != 4 {
+			t.Errorf("%q: screen has %d rows", seq, len(s.cells))
+		}
+	}
+}
+
+func TestVTScreenSplitUTF8(t *testing.T) {
+	s := newVTScreen(1, 10)
+	data := []byte("дом")
+	for _, b := range data {
+		s.Write([]byte{b})
+	}
+	if got := vtText(s)[0]; got != "дом" {
+		t.Errorf("byte-by-byte UTF-8 = %q, want %q", got, "дом")
+	}
+}
+
+func TestVTScreenReply(t *testing.T) {
+	s := newVTScreen(5, 10)
+	s.Write([]byte("\x1b[3;4H\x1b[6n"))
+	if got := string(s.reply); got != "\x1b[3;4R" {
+		t.Errorf("cursor position report = %q", got)
+	}
+}
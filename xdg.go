@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// desktopEntry — нужная нам часть .desktop-файла.
+type desktopEntry struct {
+	ID       string // имя файла относительно applications/, например org.gnome.eog.desktop
+	Name     string
+	Exec     string
+	Terminal bool
+	MimeType []string
+}
+
+// openWithMenu — состояние выбора приложения для открытия файла.
+type openWithMenu struct {
+	path     string
+	mimeType string
+	handlers []desktopEntry
+	cursor   int
+}
+
+type openHandlerMsg struct {
+	Path  string
+	Entry desktopEntry
+	Err   error
+}
+
+type openWithMsg struct {
+	Menu *openWithMenu
+}
+
+type openDoneMsg struct {
+	Path string
+	App  string
+	Err  error
+}
+
+// detectMIME определяет MIME-тип файла по сигнатуре и расширению.
+func detectMIME(path string) string {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		return "inode/directory"
+	}
+
+	byExt := mime.TypeByExtension(filepath.Ext(path))
+
+	byMagic := ""
+	if f, err := os.Open(path); err == nil {
+		buf := make([]byte, 512)
+		n, _ := f.Read(buf)
+		f.Close()
+		if n > 0 {
+			byMagic = http.DetectContentType(buf[:n])
+		}
+	}
+
+	// Сигнатура надёжнее расширения, но для текста и неизвестных данных
+	// она слишком общая — тогда доверяем расширению.
+	generic := byMagic == "" || byMagic == "application/octet-stream" || strings.HasPrefix(byMagic, "text/plain")
+	t := byMagic
+	if generic && byExt != "" {
+		t = byExt
+	}
+	if t == "" {
+		t = "application/octet-stream"
+	}
+	if i := strings.Index(t, ";"); i >= 0 {
+		t = t[:i]
+	}
+	return strings.TrimSpace(t)
+}
+
+func xdgDir(env, fallback string) string {
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	return filepath.Join(os.Getenv("HOME"), fallback)
+}
+
+func xdgDirs(env, fallback string) []string {
+	v := os.Getenv(env)
+	if v == "" {
+		v = fallback
+	}
+	return filepath.SplitList(v)
+}
+
+// applicationDirs — каталоги .desktop-файлов в порядке убывания приоритета.
+func applicationDirs() []string {
+	dirs := []string{filepath.Join(xdgDir("XDG_DATA_HOME", ".local/share"), "applications")}
+	for _, d := range xdgDirs("XDG_DATA_DIRS", "/usr/local/share:/usr/share") {
+		dirs = append(dirs, filepath.Join(d, "applications"))
+	}
+	return dirs
+}
+
+// mimeappsFiles — файлы mimeapps.list в порядке убывания приоритета.
+func mimeappsFiles() []string {
+	files := []string{filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "mimeapps.list")}
+	for _, d := range xdgDirs("XDG_CONFIG_DIRS", "/etc/xdg") {
+		files = append(files, filepath.Join(d, "mimeapps.list"))
+	}
+	for _, d := range applicationDirs() {
+		files = append(files, filepath.Join(d, "mimeapps.list"), filepath.Join(d, "defaults.list"))
+	}
+	return files
+}
+
+// parseIni читает простой ini-файл в формате секция → ключ → значение.
+func parseIni(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sections := make(map[string]map[string]string)
+	section := ""
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if sections[section] == nil {
+			sections[section] = make(map[string]string)
+		}
+		k = strings.TrimSpace(k)
+		if _, exists := sections[section][k]; !exists {
+			sections[section][k] = strings.TrimSpace(v)
+		}
+	}
+	return sections, sc.Err()
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ";") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// loadDesktopEntries читает все .desktop-файлы; первый найденный ID имеет приоритет.
+func loadDesktopEntries() map[string]desktopEntry {
+	entries := make(map[string]desktopEntry)
+	for _, dir := range applicationDirs() {
+		_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(path, ".desktop") {
+				return nil
+			}
+			rel, _ := filepath.Rel(dir, path)
+			id := strings.ReplaceAll(rel, string(filepath.Separator), "-")
+			if _, ok := entries[id]; ok {
+				return nil
+			}
+			ini, err := parseIni(path)
+			if err != nil {
+				return nil
+			}
+			sec := ini["Desktop Entry"]
+			if sec == nil || sec["Exec"] == "" || sec["Hidden"] == "true" {
+				return nil
+			}
+			entries[id] = desktopEntry{
+				ID:       id,
+				Name:     sec["Name"],
+				Exec:     sec["Exec"],
+				Terminal: sec["Terminal"] == "true",
+				MimeType: splitList(sec["MimeType"]),
+			}
+			return nil
+		})
+	}
+	return entries
+}
+
+// mimeHandlers возвращает приложения для MIME-типа: сначала по умолчанию, затем прочие.
+func mimeHandlers(mimeType string) []desktopEntry {
+	entries := loadDesktopEntries()
+
+	var ordered []string
+	seen := make(map[string]bool)
+	removed := make(map[string]bool)
+	add := func(id string) {
+		if !seen[id] && !removed[id] {
+			if _, ok := entries[id]; ok {
+				seen[id] = true
+				ordered = append(ordered, id)
+			}
+		}
+	}
+
+	types := []string{mimeType}
+	if strings.HasPrefix(mimeType, "text/") && mimeType != "text/plain" {
+		types = append(types, "text/plain")
+	}
+
+	for _, t := range types {
+		for _, file := range mimeappsFiles() {
+			ini, err := parseIni(file)
+			if err != nil {
+				continue
+			}
+			for _, id := range splitList(ini["Removed Associations"][t]) {
+				removed[id] = true
+			}
+			for _, id := range splitList(ini["Default Applications"][t]) {
+				add(id)
+			}
+			for _, id := range splitList(ini["Added Associations"][t]) {
+				add(id)
+			}
+		}
+
+		var rest []string
+		for id, e := range entries {
+			for _, mt := range e.MimeType {
+				if mt == t {
+					rest = append(rest, id)
+					break
+				}
+			}
+		}
+		sort.Strings(rest)
+		for _, id := range rest {
+			add(id)
+		}
+	}
+
+	handlers := make([]desktopEntry, 0, len(ordered))
+	for _, id := range ordered {
+		handlers = append(handlers, entries[id])
+	}
+	return handlers
+}
+
+// splitExec разбивает строку Exec с учётом кавычек и экранирования.
+func splitExec(s string) []string {
+	var args []string
+	var cur strings.Builder
+	inQuote, escaped, has := false, false, false
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			inQuote = !inQuote
+			has = true
+		case r == ' ' && !inQuote:
+			if has || cur.Len() > 0 {
+				args = append(args, cur.String())
+				cur.Reset()
+				has = false
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if has || cur.Len() > 0 {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
+// execArgs подставляет путь в коды полей строки Exec (%f, %F, %u, %U).
+func execArgs(e desktopEntry, path string) []string {
+	var args []string
+	substituted := false
+	for _, a := range splitExec(e.Exec) {
+		switch a {
+		case "%f", "%F", "%u", "%U":
+			args = append(args, path)
+			substituted = true
+			continue
+		case "%i", "%c", "%k", "%d", "%D", "%n", "%N", "%v", "%m":
+			continue
+		}
+		a = strings.NewReplacer("%f", path, "%F", path, "%u", path, "%U", path, "%c", e.Name, "%%", "%").Replace(a)
+		if strings.Contains(a, path) {
+			substituted = true
+		}
+		args = append(args, a)
+	}
+	if !substituted {
+		args = append(args, path)
+	}
+	return args
+}
+
+// launchEntry запускает приложение: терминальные — с приостановкой TUI, графические — отдельно от нас.
+func launchEntry(e desktopEntry, path string) tea.Cmd {
+	args := execArgs(e, path)
+	if len(args) == 0 {
+		return func() tea.Msg {
+			return openDoneMsg{Path: path, App: e.ID, Err: fmt.Errorf("empty Exec in %s", e.ID)}
+		}
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = filepath.Dir(path)
+
+	if e.Terminal {
+		return tea.ExecProcess(cmd, func(err error) tea.Msg {
+			return openDoneMsg{Path: path, App: e.Name, Err: err}
+		})
+	}
+	return func() tea.Msg {
+		return openDoneMsg{Path: path, App: e.Name, Err: startDetached(cmd)}
+	}
+}
+
+// openFileCmd ищет приложение по умолчанию для MIME-типа файла.
+// Запуск делает Update по openHandlerMsg: терминальным программам нужен tea.ExecProcess.
+func openFileCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		local, err := localPath(path)
+		if err != nil {
+			return openHandlerMsg{Path: path, Err: err}
+		}
+		path = local
+		mimeType := detectMIME(path)
+		if handlers := mimeHandlers(mimeType); len(handlers) > 0 {
+			return openHandlerMsg{Path: path, Entry: handlers[0]}
+		}
+		if _, err := exec.LookPath("xdg-open"); err == nil {
+			return openHandlerMsg{Path: path, Entry: desktopEntry{ID: "xdg-open", Name: "xdg-open", Exec: "xdg-open %f"}}
+		}
+		return openHandlerMsg{Path: path, Err: fmt.Errorf("no application registered for %s", mimeType)}
+	}
+}
+
+// openWithCmd собирает список всех приложений для файла.
+func openWithCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		local, err := localPath(path)
+		if err != nil {
+			return openHandlerMsg{Path: path, Err: err}
+		}
+		path = local
+		mimeType := detectMIME(path)
+		return openWithMsg{Menu: &openWithMenu{
+			path:     path,
+			mimeType: mimeType,
+			handlers: mimeHandlers(mimeType),
+		}}
+	}
+}
+
+func (m model) updateOpenWith(msg tea.KeyMsg) (model, tea.Cmd) {
+	menu := m.openWith
+	switch msg.String() {
+	case "esc", "q":
+		m.openWith = nil
+	case "up", "k":
+		if menu.cursor > 0 {
+			menu.cursor--
+		}
+	case "down", "j":
+		if menu.cursor < len(menu.handlers)-1 {
+			menu.cursor++
+		}
+	case "enter":
+		m.openWith = nil
+		if len(menu.handlers) == 0 {
+			return m, nil
+		}
+		return m, launchEntry(menu.handlers[menu.cursor], menu.path)
+	}
+	return m, nil
+}
+
+func (m model) renderOpenWith() string {
+	menu := m.openWith
+	popupWidth := 60
+
+	popupStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("171")).
+		Padding(1, 2).
+		Width(popupWidth)
+
+	title := lipgloss.NewStyle().Bold(true).Render("Open " + filepath.Base(menu.path) + " with…")
+	sub := lipgloss.NewStyle().Faint(true).Render(menu.mimeType)
+
+	var b strings.Builder
+	if len(menu.handlers) == 0 {
+		b.WriteString("No registered applications.\n")
+	}
+	for i, h := range menu.handlers {
+		name := h.Name
+		if h.Terminal {
+			name += " (terminal)"
+		}
+		if i == 0 {
+			name += " — default"
+		}
+		if i == menu.cursor {
+			b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("171")).Bold(true).Render("● " + name))
+		} else {
+			b.WriteString("  " + name)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n" + lipgloss.NewStyle().Faint(true).Render("↑/↓ choose • Enter open • Esc cancel"))
+
+	popup := popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, title, sub, "", b.String()))
+
+	x := (m.width - popupWidth) / 2
+	y := (m.height - len(menu.handlers) - 9) / 2
+	if y < 0 {
+		y = 0
+	}
+	return lipgloss.NewStyle().MarginLeft(x).MarginTop(y).Render(popup)
+}